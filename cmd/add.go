@@ -21,21 +21,35 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/cert-manager/klone/pkg/config"
 	"github.com/cert-manager/klone/pkg/mod"
 )
 
 func NewAddCommand() *cobra.Command {
+	var (
+		include, exclude []string
+		output           string
+	)
+
 	cmds := &cobra.Command{
 		Use:   "add dst_path dst_folder_name repo_url repo_path repo_ref [repo_hash]",
-		Short: "Add a new target to sync from an upstream git repository",
+		Short: "Add a new target to sync from an upstream git or OCI repository",
 		Example: `Sync the 'logo' directory from the main branch of the cert-manager
 community repository to the local directory ./a/b
 
   klone add a b https://github.com/cert-manager/community.git logo main
     or with pinned commit hash:
-  klone add a b https://github.com/cert-manager/community.git logo main 9f0ea0341816665feadcdcfb7744f4245604ab28`,
+  klone add a b https://github.com/cert-manager/community.git logo main 9f0ea0341816665feadcdcfb7744f4245604ab28
+    or without the README files:
+  klone add a b https://github.com/cert-manager/community.git logo main --exclude '**/README.md'
+    or from the v1.0.0 tag of an OCI artifact:
+  klone add a b oci://ghcr.io/cert-manager/bundles logo v1.0.0`,
 		Args: cobra.RangeArgs(5, 6),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
 			workDirPath, err := filepath.Abs(".")
 			if err != nil {
 				return err
@@ -54,14 +68,49 @@ community repository to the local directory ./a/b
 				repoHash = args[5]
 			}
 
-			return workDir.AddTarget(dstPath, dstFolderName, mod.KloneSource{
+			src := mod.KloneSource{
 				RepoURL:  repoURL,
 				RepoPath: repoPath,
 				RepoRef:  repoRef,
 				RepoHash: repoHash,
-			})
+				Include:  include,
+				Exclude:  exclude,
+			}
+			if err := workDir.AddTarget(dstPath, dstFolderName, src); err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				// nothing is fetched yet, so repo_hash stays empty unless it
+				// was given; "klone sync" resolves and copies the new item
+				return writeJSON(cmd.OutOrStdout(), struct {
+					Target     string   `json:"target"`
+					FolderName string   `json:"folder_name"`
+					RepoURL    string   `json:"repo_url"`
+					RepoRef    string   `json:"repo_ref"`
+					RepoHash   string   `json:"repo_hash"`
+					RepoPath   string   `json:"repo_path"`
+					Include    []string `json:"include,omitempty"`
+					Exclude    []string `json:"exclude,omitempty"`
+				}{
+					Target:     dstPath,
+					FolderName: dstFolderName,
+					RepoURL:    config.RedactURL(src.RepoURL),
+					RepoRef:    src.RepoRef,
+					RepoHash:   src.RepoHash,
+					RepoPath:   src.RepoPath,
+					Include:    src.Include,
+					Exclude:    src.Exclude,
+				})
+			}
+
+			return nil
 		},
 	}
 
+	cmds.Flags().StringSliceVar(&include, "include", nil, "only klone files matching these glob patterns (relative to repo_path, \"**\" matches any number of directories)")
+	cmds.Flags().StringSliceVar(&exclude, "exclude", nil, "do not klone files matching these glob patterns (relative to repo_path, \"**\" matches any number of directories)")
+	addOutputFlag(cmds, &output)
+
 	return cmds
 }
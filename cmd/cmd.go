@@ -17,10 +17,21 @@ limitations under the License.
 package cmd
 
 import (
+	"log/slog"
+
 	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/logging"
 )
 
+// loadsConfigAnnotation marks commands that load the klone config
+// themselves instead of failing on an invalid one before they run.
+const loadsConfigAnnotation = "klone/loads-config"
+
 func NewCommand() *cobra.Command {
+	var quiet, verbose bool
+
 	cmds := &cobra.Command{
 		Use:   "klone",
 		Short: "Clone folders from a git repo locally",
@@ -44,13 +55,65 @@ the "klone add" command above, you'll see that the "example/myfolder" directory
 has been populated from the remote git repository.
 
 If there's an upstream update later, "klone upgrade" will fetch the latest
-revision for the upstream and check out the results locally.`,
+revision for the upstream and check out the results locally. To review such an
+update first, "klone diff --upstream" prints it as a unified diff.
+
+To check that nobody edited the kloned folders by hand, "klone verify" compares
+them with the pinned upstream revisions without modifying anything.
+
+To keep resolved hashes out of the hand-maintained klone.yaml, run "klone lock"
+which moves them to a klone.lock file that is updated from then on.
+
+Global settings, such as the cache directory, are read from
+~/.config/klone/config.yaml, run "klone config" to show them.
+
+Progress is logged to stderr; use -q to only log warnings and errors, or -v
+to also log every git command and its output.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			level := slog.LevelInfo
+			switch {
+			case quiet:
+				level = slog.LevelWarn
+			case verbose:
+				level = slog.LevelDebug
+			}
+
+			logger := logging.New(cmd.ErrOrStderr(), level)
+			ctx := logging.IntoContext(cmd.Context(), logger)
+			if cmd.Annotations[loadsConfigAnnotation] != "" {
+				cmd.SetContext(ctx)
+				return nil
+			}
+
+			// the config is read once, so that a command uses the same
+			// settings for all of its operations
+			cfg, err := config.Load()
+			if err != nil {
+				// an invalid config is not a usage error
+				cmd.SilenceUsage = true
+				return err
+			}
+
+			cmd.SetContext(config.IntoContext(ctx, cfg))
+			return nil
+		},
 	}
 
+	cmds.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "only log warnings and errors")
+	cmds.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "also log every git command and its output")
+	cmds.MarkFlagsMutuallyExclusive("quiet", "verbose")
+
 	cmds.AddCommand(NewInitCommand())
 	cmds.AddCommand(NewSyncCommand())
 	cmds.AddCommand(NewAddCommand())
+	cmds.AddCommand(NewRemoveCommand())
 	cmds.AddCommand(NewUpgradeCommand())
+	cmds.AddCommand(NewVerifyCommand())
+	cmds.AddCommand(NewStatusCommand())
+	cmds.AddCommand(NewLockCommand())
+	cmds.AddCommand(NewPatchCommand())
+	cmds.AddCommand(NewDiffCommand())
+	cmds.AddCommand(NewConfigCommand())
 
 	return cmds
 }
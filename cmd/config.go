@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+func NewConfigCommand() *cobra.Command {
+	var output string
+
+	cmds := &cobra.Command{
+		Use:   "config",
+		Short: "Show the effective global settings and where they were configured",
+		Long: `Show the effective global settings and where they were configured
+
+The global settings are read from config.yaml in the klone directory of
+$XDG_CONFIG_HOME (~/.config/klone/config.yaml by default), or from the file
+named by KLONE_CONFIG, e.g.:
+
+  cache_dir: ~/.cache/klone   # KLONE_CACHE_DIR
+  cache_ttl: 168h             # KLONE_CACHE_TTL
+  retry_attempts: 3           # KLONE_GIT_RETRY_ATTEMPTS
+  jobs: 4                     # KLONE_JOBS
+  git_backend: exec           # KLONE_GIT_BACKEND, "exec" or "go-git"
+  mirrors:                    # KLONE_MIRRORS, as "instead_of=url,..."
+    - url: https://git.example.com/github/
+      instead_of: https://github.com/
+  credentials:                # only in the config file
+    - host: github.com
+      token_env: GITHUB_TOKEN # or token_file, and optionally username
+    - host: gitlab.example.com
+      ssh_key: ~/.ssh/id_klone
+
+Every setting except credentials can be overridden by the env var in the
+comment. Invalid values are an error for every klone command except this
+one, which shows the error of each invalid setting.
+
+The cache is in the klone directory of $XDG_CACHE_HOME (~/.cache/klone by
+default). An existing ~/.cache/klone stays in use until that directory
+exists, since older versions of klone ignored XDG_CACHE_HOME.
+
+Mirrors replace the longest matching instead_of prefix of a git repo_url
+with their url before it is fetched, like the insteadOf setting of git.
+klone.yaml may list mirrors of its own in the same format, which win over
+the global mirrors with the same prefix. The repo_url in klone.yaml and the
+klone cache are not affected.
+
+Credentials are used for the git remotes on their host, after mirrors are
+applied. A token is read from token_env or token_file when it is needed and
+sent as HTTP basic auth with the username x-access-token by default; an
+ssh_key is used for ssh remotes. Tokens are passed to git in its
+environment, never in its arguments or in log output.`,
+		Args: cobra.ExactArgs(0),
+		// an invalid config is shown instead of failing the command
+		Annotations: map[string]string{loadsConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			// the config file error, if any, is shown along with the
+			// settings of the defaults and env vars
+			cfg, fileErr := config.Inspect()
+			settings := cfg.Settings()
+
+			invalid := fileErr != nil
+			for _, setting := range settings {
+				if setting.Error != "" {
+					invalid = true
+				}
+			}
+
+			if output == outputJSON {
+				var errMsg string
+				if fileErr != nil {
+					errMsg = fileErr.Error()
+				}
+				if err := writeJSON(cmd.OutOrStdout(), struct {
+					Path     string           `json:"path"`
+					Error    string           `json:"error,omitempty"`
+					Settings []config.Setting `json:"settings"`
+				}{cfg.Path, errMsg, settings}); err != nil {
+					return err
+				}
+			} else {
+				path := cfg.Path
+				switch _, err := os.Stat(path); {
+				case path == "":
+					path = "- (KLONE_CONFIG is not set and there is no home directory)"
+				case fileErr != nil:
+					path += " (" + fileErr.Error() + ")"
+				case errors.Is(err, fs.ErrNotExist):
+					path += " (does not exist)"
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Config file: %s\n\n", path)
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprint(w, "SETTING\tVALUE\tORIGIN")
+				if invalid {
+					fmt.Fprint(w, "\tERROR")
+				}
+				fmt.Fprintln(w)
+				for _, setting := range settings {
+					value := setting.Value
+					if value == "" {
+						value = "-"
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s", setting.Name, value, setting.Origin)
+					if invalid {
+						fmt.Fprintf(w, "\t%s", setting.Error)
+					}
+					fmt.Fprintln(w)
+				}
+				if err := w.Flush(); err != nil {
+					return err
+				}
+			}
+
+			if invalid {
+				cmd.SilenceUsage = true
+				return errors.New("the klone config is invalid")
+			}
+			return nil
+		},
+	}
+
+	addOutputFlag(cmds, &output)
+
+	return cmds
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/mod"
+	"github.com/cert-manager/klone/pkg/sync"
+)
+
+func NewDiffCommand() *cobra.Command {
+	var (
+		selector mod.Selector
+		upstream bool
+	)
+
+	cmds := &cobra.Command{
+		Use:   "diff [dst_path...]",
+		Args:  cobra.ArbitraryArgs,
+		Short: "Show a unified diff between the local folders, the pinned upstream and the latest upstream",
+		Long: `Show a unified diff between the local folders, the pinned upstream and the latest upstream
+
+By default, the pinned upstream content (with its patches applied) is
+compared with the local folders, showing the local modifications that
+"klone sync" would revert. With --upstream, the pinned upstream content is
+compared with the content that repo_ref currently refers to, showing what
+"klone upgrade" would change.
+
+Pass one or more destination paths (a target or target/folder_name) or use
+the selector flags to only diff a subset of the items. The upstream content
+is fetched into the klone cache.`,
+		Example: `Review the changes an upgrade of the "go" makefile module would make
+
+  klone diff --upstream make/_shared/go`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDirPath, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+
+			selector.Paths = args
+
+			mode := sync.DiffLocal
+			if upstream {
+				mode = sync.DiffUpstream
+			}
+
+			items, err := sync.DiffFolder(cmd.Context(), workDirPath, selector, mode, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+
+			// the summary goes to stderr, so that stdout is a valid patch
+			for _, item := range items {
+				if mode == sync.DiffUpstream {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: repo_hash %s -> %s\n",
+						filepath.Join(item.Target, item.FolderName), shortHash(item.OldHash), tagAndHash(item.NewTag, item.NewHash))
+				} else {
+					fmt.Fprintf(cmd.ErrOrStderr(), "%s: differs from %s\n",
+						filepath.Join(item.Target, item.FolderName), shortHash(item.OldHash))
+				}
+			}
+			if len(items) == 0 {
+				fmt.Fprintln(cmd.ErrOrStderr(), "No differences")
+			}
+
+			return nil
+		},
+	}
+
+	addSelectorFlags(cmds, &selector)
+	cmds.Flags().BoolVar(&upstream, "upstream", false, "compare the pinned upstream with the latest upstream of repo_ref instead of with the local folders")
+
+	return cmds
+}
@@ -25,20 +25,40 @@ import (
 )
 
 func NewInitCommand() *cobra.Command {
+	var output string
+
 	cmds := &cobra.Command{
 		Use:   "init",
 		Short: "Initialise a new klone.yaml file and exit",
 		Args:  cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
 			workDirPath, err := filepath.Abs(".")
 			if err != nil {
 				return err
 			}
 
 			workDir := mod.WorkDir(workDirPath)
-			return workDir.Init()
+			if err := workDir.Init(); err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), struct {
+					Path string `json:"path"`
+				}{
+					Path: filepath.Join(workDirPath, "klone.yaml"),
+				})
+			}
+
+			return nil
 		},
 	}
 
+	addOutputFlag(cmds, &output)
+
 	return cmds
 }
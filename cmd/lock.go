@@ -0,0 +1,52 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func NewLockCommand() *cobra.Command {
+	cmds := &cobra.Command{
+		Use:   "lock",
+		Short: "Move all resolved hashes from klone.yaml into a klone.lock file",
+		Long: `Move all resolved hashes from klone.yaml into a klone.lock file
+
+Once a klone.lock file exists next to klone.yaml, "klone sync" and
+"klone upgrade" record resolved commit hashes and content hashes in
+klone.lock only, so that klone.yaml just contains the hand-maintained
+repo_url, repo_ref and repo_path of every item, and the sha256 of archives.
+A repo_hash or content_hash that is set in klone.yaml still takes precedence
+and is moved to klone.lock on the next run.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDirPath, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+
+			workDir := mod.WorkDir(workDirPath)
+			return workDir.MigrateToLockFile()
+		},
+	}
+
+	return cmds
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/sync"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+func addOutputFlag(cmds *cobra.Command, output *string) {
+	cmds.Flags().StringVarP(output, "output", "o", outputTable, "output format, one of: table, json")
+}
+
+func validateOutput(output string) error {
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("unsupported output format %q, must be %q or %q", output, outputTable, outputJSON)
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeReport writes report as JSON, with empty lists instead of nulls.
+// Progress and git output are written to stderr, so stdout only contains
+// the report.
+func writeReport(w io.Writer, report *sync.Report) error {
+	out := *report
+	if out.Removed == nil {
+		out.Removed = []string{}
+	}
+
+	out.Items = make([]sync.ItemReport, len(report.Items))
+	for i, item := range report.Items {
+		if item.Changes == nil {
+			item.Changes = []sync.Change{}
+		}
+		out.Items[i] = item
+	}
+
+	return writeJSON(w, out)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/sync"
+)
+
+func NewPatchCommand() *cobra.Command {
+	cmds := &cobra.Command{
+		Use:   "patch dst_path dst_folder_name patch_file",
+		Short: "Save the local modifications of a folder as a patch that is re-applied on every sync",
+		Long: `Save the local modifications of a folder as a patch that is re-applied on every sync
+
+The difference between the pinned upstream content and the local folder is
+written to patch_file, which becomes the only entry in the "patches" list of
+the item in klone.yaml. "klone sync" and "klone upgrade" apply the patches in
+order after copying the upstream content, and fail if a patch no longer
+applies. In that case, fix the files by hand and run this command again.
+
+Patches are written in the format of "git diff --binary"; hand-written
+patches may also be plain unified diffs. Applying and creating patches does
+not require git.`,
+		Example: `Keep a local fix to the "go" makefile module while it is being upstreamed
+
+  klone patch make/_shared go make/patches/go.patch`,
+		Args: cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDirPath, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+
+			return sync.CreatePatch(cmd.Context(), workDirPath, args[0], args[1], args[2])
+		},
+	}
+
+	return cmds
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/sync"
+)
+
+func NewRemoveCommand() *cobra.Command {
+	var force bool
+
+	cmds := &cobra.Command{
+		Use:   "remove dst_path [dst_folder_name]",
+		Short: "Remove a target or a single folder and delete its local files",
+		Example: `Remove the folder ./a/b that was added with "klone add a b ..."
+
+  klone remove a b
+    or remove every folder of the ./a target:
+  klone remove a`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDirPath, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+
+			dstPath := args[0]
+
+			dstFolderName := ""
+			if len(args) == 2 {
+				dstFolderName = args[1]
+			}
+
+			return sync.RemoveFolder(workDirPath, dstPath, dstFolderName, force)
+		},
+	}
+
+	cmds.Flags().BoolVar(&force, "force", false, "delete files in the removed folders that were modified since klone last wrote them")
+
+	return cmds
+}
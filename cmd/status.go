@@ -0,0 +1,131 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/sync"
+)
+
+func NewStatusCommand() *cobra.Command {
+	var (
+		remote bool
+		output string
+	)
+
+	cmds := &cobra.Command{
+		Use:     "status",
+		Aliases: []string{"list"},
+		Short:   "List every pinned item and whether it is up to date",
+		Long: `List every pinned item and whether it is up to date
+
+For every item in klone.yaml, the STATE column shows how the local folder
+compares with the pinned content in the klone cache:
+
+  in-sync     the folder matches the pinned content
+  drifted     the folder differs from the pinned content
+  missing     the folder does not exist, run "klone sync"
+  not-cached  the pinned content is not cached, run "klone verify" to compare
+  unpinned    the item has no repo_hash yet, run "klone sync"
+
+With --remote, the latest commit of every repo_ref is looked up as well.`,
+		Args: cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
+			workDirPath, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+
+			statuses, err := sync.Status(cmd.Context(), workDirPath, remote)
+			if err != nil {
+				return err
+			}
+
+			if output == outputJSON {
+				if statuses == nil {
+					statuses = []sync.ItemStatus{}
+				}
+
+				return writeJSON(cmd.OutOrStdout(), statuses)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			if remote {
+				fmt.Fprintln(w, "TARGET\tFOLDER\tREPO_URL\tREPO_REF\tHASH\tSTATE\tLATEST")
+			} else {
+				fmt.Fprintln(w, "TARGET\tFOLDER\tREPO_URL\tREPO_REF\tHASH\tSTATE")
+			}
+
+			for _, status := range statuses {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s",
+					status.Target, status.FolderName, status.RepoURL, status.RepoRef, tagAndHash(status.RepoTag, status.RepoHash), status.State)
+				if remote {
+					latest := "up-to-date"
+					switch {
+					case status.Outdated == nil:
+						latest = "-"
+					case *status.Outdated:
+						latest = tagAndHash(status.LatestTag, status.LatestHash)
+					}
+					fmt.Fprintf(w, "\t%s", latest)
+				}
+				fmt.Fprintln(w)
+			}
+
+			return w.Flush()
+		},
+	}
+
+	cmds.Flags().BoolVar(&remote, "remote", false, "look up the latest commit of every repo_ref")
+	addOutputFlag(cmds, &output)
+
+	return cmds
+}
+
+func tagAndHash(tag string, hash string) string {
+	if tag == "" {
+		return shortHash(hash)
+	}
+	return tag + " (" + shortHash(hash) + ")"
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	// keep the algorithm of digests, e.g. "sha256:"
+	prefix, digest, ok := strings.Cut(hash, ":")
+	if !ok {
+		prefix, digest = "", hash
+	} else {
+		prefix += ":"
+	}
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+	return prefix + digest
+}
@@ -17,27 +17,145 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 
+	"github.com/cert-manager/klone/pkg/mod"
 	"github.com/cert-manager/klone/pkg/sync"
 )
 
 func NewSyncCommand() *cobra.Command {
+	var (
+		selector mod.Selector
+		dryRun   bool
+		jobs     int
+		force    bool
+		output   string
+	)
+
 	cmds := &cobra.Command{
-		Use:   "sync",
+		Use:   "sync [dst_path...]",
 		Short: "Ensure the local state of targets matches upstream",
-		Args:  cobra.ExactArgs(0),
+		Long: `Ensure the local state of targets matches upstream
+
+By default all targets are synced. Pass one or more destination paths (a
+target or target/folder_name) or use the selector flags to only sync a subset
+of the items; items that are not selected are left untouched.
+
+To test changes to an upstream without pushing them, create an uncommitted
+klone.override.yaml next to klone.yaml (or point KLONE_OVERRIDE_FILE at one)
+that replaces a repo_url or target/folder_name with a local checkout:
+
+  replace:
+    https://github.com/cert-manager/makefile-modules.git: ../makefile-modules
+    make/_shared/go: ../makefile-modules
+
+Overridden items are copied from the checkout and are never written to
+klone.yaml; a warning is printed while overrides are active.
+
+Klone remembers the files it wrote in .klone/manifest.json. If a kloned file
+was modified or added by hand since then, the sync fails without changing
+anything; pass --force to discard such changes, or save them with
+"klone patch".
+
+With --output json, a report of every selected item is printed to stdout,
+also if the sync fails; progress and git output are printed to stderr.`,
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
 			workDirPath, err := filepath.Abs(".")
 			if err != nil {
 				return err
 			}
 
-			return sync.SyncFolder(cmd.Context(), workDirPath, false)
+			selector.Paths = args
+
+			report, err := sync.SyncFolder(cmd.Context(), workDirPath, sync.Options{
+				Selector: selector,
+				DryRun:   dryRun,
+				Jobs:     jobs,
+				Force:    force,
+			})
+			if output == outputJSON && report != nil {
+				if jsonErr := writeReport(cmd.OutOrStdout(), report); jsonErr != nil {
+					return jsonErr
+				}
+				return err
+			}
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				printPlan(cmd.OutOrStdout(), report)
+			}
+
+			return nil
 		},
 	}
 
+	addSelectorFlags(cmds, &selector)
+	addDryRunFlag(cmds, &dryRun)
+	addJobsFlag(cmds, &jobs)
+	addForceFlag(cmds, &force)
+	addOutputFlag(cmds, &output)
+
 	return cmds
 }
+
+func addSelectorFlags(cmds *cobra.Command, selector *mod.Selector) {
+	cmds.Flags().StringSliceVar(&selector.Targets, "target", nil, "only select items of these targets (dst_path)")
+	cmds.Flags().StringSliceVar(&selector.Folders, "folder", nil, "only select items with these folder names (dst_folder_name)")
+	cmds.Flags().StringSliceVar(&selector.RepoURLs, "repo-url", nil, "only select items kloned from these repositories")
+}
+
+func addDryRunFlag(cmds *cobra.Command, dryRun *bool) {
+	cmds.Flags().BoolVar(dryRun, "dry-run", false, "print the planned deletions, copies and hash bumps without modifying anything")
+}
+
+func addJobsFlag(cmds *cobra.Command, jobs *int) {
+	cmds.Flags().IntVarP(jobs, "jobs", "j", 0, "number of items to resolve and download concurrently (default: jobs of the klone config)")
+}
+
+func addForceFlag(cmds *cobra.Command, force *bool) {
+	cmds.Flags().BoolVar(force, "force", false, "overwrite or delete files in the kloned folders that were modified since klone last wrote them")
+}
+
+// printPlan prints the changes of a dry run.
+func printPlan(w io.Writer, report *sync.Report) {
+	changed := false
+
+	for _, removed := range report.Removed {
+		fmt.Fprintf(w, "%s: would be removed, it is not listed in klone.yaml\n", removed)
+		changed = true
+	}
+
+	for _, item := range report.Items {
+		if item.OldHash == item.NewHash && len(item.Changes) == 0 {
+			continue
+		}
+		changed = true
+
+		fmt.Fprintf(w, "%s:\n", filepath.Join(item.Target, item.FolderName))
+		if item.Override != "" {
+			fmt.Fprintf(w, "  overridden by %s\n", item.Override)
+		}
+		if item.OldHash != item.NewHash {
+			fmt.Fprintf(w, "  repo_hash %s -> %s\n", shortHash(item.OldHash), tagAndHash(item.NewTag, item.NewHash))
+		}
+
+		for _, change := range item.Changes {
+			fmt.Fprintf(w, "  %-8s %s\n", change.Kind, change.Path)
+		}
+	}
+
+	if !changed {
+		fmt.Fprintln(w, "Nothing to do, all selected items are in sync")
+	}
+}
@@ -17,27 +17,124 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/cert-manager/klone/pkg/mod"
 	"github.com/cert-manager/klone/pkg/sync"
 )
 
 func NewUpgradeCommand() *cobra.Command {
+	var (
+		selector mod.Selector
+		dryRun   bool
+		jobs     int
+		force    bool
+		markdown bool
+		output   string
+	)
+
 	cmds := &cobra.Command{
-		Use:   "upgrade",
-		Args:  cobra.ExactArgs(0),
+		Use:   "upgrade [dst_path...]",
+		Args:  cobra.ArbitraryArgs,
 		Short: "Update all hashes to the latest upstream available and sync",
+		Long: `Update all hashes to the latest upstream available and sync
+
+By default all targets are upgraded. Pass one or more destination paths (a
+target or target/folder_name) or use the selector flags to only upgrade a
+subset of the items; items that are not selected are left untouched.
+
+For every item kloned from git whose repo_hash changes, the upstream commits
+between the old and the new hash that touch its repo_path are printed. Use
+--markdown to print them in a format suitable for a pull request description.
+
+With --output json, a report of every selected item, including its upstream
+commits, is printed to stdout, also if the upgrade fails; progress and git
+output are printed to stderr.`,
+		Example: `Only upgrade the items that are kloned from makefile-modules
+
+  klone upgrade --repo-url https://github.com/cert-manager/makefile-modules.git
+    or only upgrade a single folder:
+  klone upgrade make/_shared/go`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutput(output); err != nil {
+				return err
+			}
+
 			workDirPath, err := filepath.Abs(".")
 			if err != nil {
 				return err
 			}
 
-			return sync.SyncFolder(cmd.Context(), workDirPath, true)
+			selector.Paths = args
+
+			report, err := sync.SyncFolder(cmd.Context(), workDirPath, sync.Options{
+				ForceUpgrade: true,
+				Selector:     selector,
+				DryRun:       dryRun,
+				Jobs:         jobs,
+				Force:        force,
+				CommitLog:    true,
+			})
+			if output == outputJSON && report != nil {
+				if jsonErr := writeReport(cmd.OutOrStdout(), report); jsonErr != nil {
+					return jsonErr
+				}
+				return err
+			}
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				printPlan(cmd.OutOrStdout(), report)
+			}
+
+			printCommitLog(cmd.OutOrStdout(), report, markdown)
+
+			return nil
 		},
 	}
 
+	addSelectorFlags(cmds, &selector)
+	addDryRunFlag(cmds, &dryRun)
+	addJobsFlag(cmds, &jobs)
+	addForceFlag(cmds, &force)
+	cmds.Flags().BoolVar(&markdown, "markdown", false, "print the upstream commits of the upgraded items as Markdown")
+	addOutputFlag(cmds, &output)
+
 	return cmds
 }
+
+// printCommitLog prints the upstream commits of every item whose hash
+// changed.
+func printCommitLog(w io.Writer, report *sync.Report, markdown bool) {
+	for _, item := range report.Items {
+		if item.OldHash == "" || item.OldHash == item.NewHash {
+			continue
+		}
+
+		name := filepath.Join(item.Target, item.FolderName)
+		if markdown {
+			fmt.Fprintf(w, "### `%s`\n\n", name)
+			fmt.Fprintf(w, "Upgraded from `%s` to `%s`.\n\n", shortHash(item.OldHash), tagAndHash(item.NewTag, item.NewHash))
+			for _, commit := range item.Commits {
+				fmt.Fprintf(w, "- %s (`%s`, %s, %s)\n", commit.Subject, shortHash(commit.Hash), commit.Author, commit.Date.Format(time.DateOnly))
+			}
+			if item.Commits != nil && len(item.Commits) == 0 {
+				fmt.Fprintln(w, "No upstream commits change the kloned files.")
+			}
+			fmt.Fprintln(w)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s: %s -> %s\n", name, shortHash(item.OldHash), tagAndHash(item.NewTag, item.NewHash))
+		for _, commit := range item.Commits {
+			fmt.Fprintf(w, "  %s %s %s: %s\n", shortHash(commit.Hash), commit.Date.Format(time.DateOnly), commit.Author, commit.Subject)
+		}
+	}
+}
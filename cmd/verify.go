@@ -0,0 +1,72 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/sync"
+)
+
+func NewVerifyCommand() *cobra.Command {
+	cmds := &cobra.Command{
+		Use:   "verify",
+		Short: "Check that the local state of targets matches the pinned upstream without modifying anything",
+		Long: `Check that the local state of targets matches the pinned upstream
+
+For every item in klone.yaml, the pinned repo_hash is fetched into the klone
+cache and compared file-by-file with the local folder. Neither the local
+folders nor klone.yaml are modified. The command exits with a non-zero status
+if any folder has drifted, which makes it suitable as a CI check.`,
+		Args:         cobra.ExactArgs(0),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDirPath, err := filepath.Abs(".")
+			if err != nil {
+				return err
+			}
+
+			drifts, err := sync.VerifyFolder(cmd.Context(), workDirPath)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, drift := range drifts {
+				fmt.Fprintf(out, "%s: differs from %s@%s (%s)\n",
+					filepath.Join(drift.Target, drift.FolderName), config.RedactURL(drift.Source.Location()), drift.Source.Pin(), drift.Source.RepoPath)
+				for _, diff := range drift.Diffs {
+					fmt.Fprintf(out, "  %-8s %s\n", diff.Kind, diff.Path)
+				}
+			}
+
+			if len(drifts) > 0 {
+				return fmt.Errorf("%d kloned folder(s) differ from klone.yaml, run \"klone sync\" to restore them", len(drifts))
+			}
+
+			fmt.Fprintln(out, "All kloned folders match klone.yaml")
+
+			return nil
+		},
+	}
+
+	return cmds
+}
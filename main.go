@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"os"
 
 	"github.com/cert-manager/klone/cmd"
 )
@@ -27,7 +28,9 @@ func main() {
 
 	cmd := cmd.NewCommand()
 
+	// cobra already prints the error, a non-zero exit code is all that is
+	// left to do (e.g. for "klone verify" used as a CI check)
 	if err := cmd.ExecuteContext(ctx); err != nil {
-		panic(err)
+		os.Exit(1)
 	}
 }
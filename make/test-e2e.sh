@@ -45,6 +45,8 @@ touch a/b/e/SHOULD_BE_DELETED
 
 "$klone_binary" sync
 
+"$klone_binary" verify
+
 if [ ! -f a/SHOULD_NOT_BE_DELETED ]; then
 	echo "Test failed: a/SHOULD_NOT_BE_DELETED not found"
 	exit 1
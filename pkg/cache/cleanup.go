@@ -17,16 +17,20 @@ limitations under the License.
 package cache
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/cert-manager/klone/pkg/config"
 )
 
-func CleanupOldCacheItems() error {
-	cacheDir, err := getCacheDir()
+func CleanupOldCacheItems(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx)
 	if err != nil {
 		return err
 	}
+	cacheDir := filepath.Clean(cfg.CacheDir)
 
 	entries, err := os.ReadDir(cacheDir)
 	if err != nil {
@@ -39,7 +43,7 @@ func CleanupOldCacheItems() error {
 			continue
 		}
 
-		if time.Since(info.ModTime()) > 7*24*time.Hour {
+		if time.Since(info.ModTime()) > cfg.CacheTTL {
 			if err := os.RemoveAll(filepath.Join(cacheDir, entry.Name())); err != nil {
 				return err
 			}
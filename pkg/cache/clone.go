@@ -20,33 +20,39 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/cert-manager/klone/pkg/config"
 	"github.com/cert-manager/klone/pkg/mod"
 )
 
 func calculateCacheKey(src mod.KloneSource) string {
-	return fmt.Sprintf("cache-%x", sha256.Sum256(fmt.Appendf(nil, "%s-%s-%s", src.RepoURL, src.RepoHash, src.RepoPath)))[:30]
-}
-
-func getCacheDir() (string, error) {
-	// TODO: add centralized config management defining env vars + maybe a global config file for klone
-	if cacheDir := os.Getenv("KLONE_CACHE_DIR"); cacheDir != "" {
-		return filepath.Abs(filepath.Clean(cacheDir))
+	key := fmt.Appendf(nil, "%s-%s-%s", src.RepoURL, src.RepoHash, src.RepoPath)
+	switch src.Kind() {
+	case mod.SourceArchive:
+		key = fmt.Appendf(nil, "archive-%s-%s-%d-%s", src.ArchiveURL, src.SHA256, src.StripComponents, src.RepoPath)
+	case mod.SourceLocal:
+		key = fmt.Appendf(nil, "local-%s-%s-%s", src.LocalDir(), src.RepoHash, src.RepoPath)
 	}
+	// filters are only appended when set, so that the keys of existing
+	// cache entries do not change
+	if len(src.Include) > 0 || len(src.Exclude) > 0 {
+		key = fmt.Appendf(key, "-%q-%q", src.Include, src.Exclude)
+	}
+	return fmt.Sprintf("cache-%x", sha256.Sum256(key))[:30]
+}
 
-	home, err := os.UserHomeDir()
+func getCacheDir(ctx context.Context) (string, error) {
+	cfg, err := config.FromContext(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Abs(filepath.Clean(filepath.Join(home, ".cache", "klone")))
+	return filepath.Clean(cfg.CacheDir), nil
 }
 
 func CloneWithCache(
@@ -55,59 +61,103 @@ func CloneWithCache(
 	src mod.KloneSource,
 	getFn func(getCtx context.Context, targetPath string, src mod.KloneSource) (string, error),
 ) error {
-	cacheDir, err := getCacheDir()
+	cachePath, err := FetchToCache(ctx, src, getFn)
 	if err != nil {
 		return err
 	}
 
+	_, err = SyncTree(ctx, cachePath, destPath)
+	return err
+}
+
+// Lookup returns the path of the cache entry for src and whether it exists,
+// without downloading anything or refreshing the entry's age.
+func Lookup(ctx context.Context, src mod.KloneSource) (string, bool, error) {
+	cacheDir, err := getCacheDir(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	cachePath := filepath.Join(cacheDir, calculateCacheKey(src))
+	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
+		return cachePath, false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	return cachePath, true, nil
+}
+
+// FetchToCache makes sure the cache contains an entry for src, downloading
+// it with getFn if it is missing, and returns the path of that entry. The
+// returned directory is shared and must be treated as read-only. If src is
+// pinned to a content hash, both new and existing entries are verified
+// against it.
+func FetchToCache(
+	ctx context.Context,
+	src mod.KloneSource,
+	getFn func(getCtx context.Context, targetPath string, src mod.KloneSource) (string, error),
+) (string, error) {
+	cacheDir, err := getCacheDir(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
-		return err
+		return "", err
 	}
 
 	cachePath := filepath.Join(cacheDir, calculateCacheKey(src))
 
 	if _, err := os.Stat(cachePath); err != nil && !os.IsNotExist(err) {
-		return err
+		return "", err
 	} else if err != nil {
 		tempDir, err := os.MkdirTemp(cacheDir, "temp-*")
 		if err != nil {
-			return err
+			return "", err
 		}
 		defer os.RemoveAll(tempDir)
 
 		outPath, err := getFn(ctx, tempDir, src)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		// remove .git folder from outPath (if it exists)
 		if err := os.RemoveAll(filepath.Join(outPath, ".git")); err != nil {
-			return err
+			return "", err
+		}
+
+		if err := filterTree(outPath, src.Include, src.Exclude); err != nil {
+			return "", err
+		}
+
+		// never add content to the cache that does not match its pin
+		if err := verifyContentHash(outPath, src); err != nil {
+			return "", err
 		}
 
 		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
-			return err
+			return "", err
 		}
 
 		if err := os.Rename(outPath, cachePath); err != nil {
-			return err
+			// another fetch of the same source, running concurrently, may
+			// have populated the cache entry first
+			if _, statErr := os.Stat(cachePath); statErr != nil {
+				return "", err
+			}
 		}
+	} else if err := verifyContentHash(cachePath, src); err != nil {
+		return "", fmt.Errorf("%w; the klone cache entry %s may have been tampered with, remove it to download it again", err, cachePath)
 	}
 
 	currentTime := time.Now()
 	if err := os.Chtimes(cachePath, currentTime, currentTime); err != nil {
-		return err
-	}
-
-	if err := os.MkdirAll(destPath, 0o755); err != nil {
-		return err
-	}
-
-	if err := runRsyncCmd(ctx, cachePath, os.Stdout, os.Stderr, "-aq", "--delete", "--safe-links", ".", destPath); err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return cachePath, nil
 }
 
 // AssertNoSymlinkInSubpath walks each component of subpath relative to root
@@ -147,21 +197,18 @@ func AssertNoSymlinkInSubpath(root, subpath string) error {
 	return nil
 }
 
-func runRsyncCmd(ctx context.Context, root string, stdout io.Writer, stderr io.Writer, args ...string) error {
-	cmd := exec.CommandContext(ctx, "rsync", args...)
-
-	cmd.Dir = root
-	cmd.Env = append(os.Environ(), cmd.Env...)
-
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
+func verifyContentHash(path string, src mod.KloneSource) error {
+	if src.ContentHash == "" {
+		return nil
+	}
 
-	if err := cmd.Start(); err != nil {
+	hash, err := HashTree(path)
+	if err != nil {
 		return err
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("rsync command failed: %v", err)
+	if hash != src.ContentHash {
+		return fmt.Errorf("%w for %s@%s (%s): pinned %s, got %s; if repo_path, include or exclude were changed, run \"klone upgrade\" to pin the new content", ErrContentHashMismatch, config.RedactURL(src.Location()), src.Pin(), src.RepoPath, src.ContentHash, hash)
 	}
 
 	return nil
@@ -17,27 +17,19 @@ limitations under the License.
 package cache
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
-)
 
-// skipIfNoSymlinks probes whether the current process/OS can create a
-// symlink and skips the test if it cannot. Windows without Developer Mode
-// (or sandboxed CI without the symlink privilege) returns EPERM/ENOTSUP
-// from os.Symlink, which would otherwise mask the real assertion under a
-// setup failure.
-func skipIfNoSymlinks(t *testing.T) {
-	t.Helper()
-	probe := t.TempDir()
-	if err := os.Symlink(probe, filepath.Join(probe, "probe")); err != nil {
-		t.Skipf("skip: symlinks unsupported in this environment: %v", err)
-	}
-}
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
 
 func TestAssertNoSymlinkInSubpath(t *testing.T) {
-	skipIfNoSymlinks(t)
+	testutil.SkipIfNoSymlinks(t)
 	root := t.TempDir()
 
 	// Layout (linkTarget = root/a/b, an in-tree dir — the planted symlinks
@@ -115,3 +107,40 @@ func TestAssertNoSymlinkInSubpath(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchToCache_Concurrent(t *testing.T) {
+	t.Setenv("KLONE_CACHE_DIR", t.TempDir())
+
+	src := mod.KloneSource{RepoURL: "https://github.com/repo", RepoHash: "abc123", RepoPath: "path"}
+	getFn := func(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
+		outPath := filepath.Join(targetPath, src.RepoPath)
+		if err := os.MkdirAll(outPath, 0o755); err != nil {
+			return "", err
+		}
+		return outPath, os.WriteFile(filepath.Join(outPath, "file"), []byte("content"), 0o644)
+	}
+
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	errs := make([]error, 8)
+	for i := range paths {
+		wg.Go(func() {
+			paths[i], errs[i] = FetchToCache(t.Context(), src, getFn)
+		})
+	}
+	wg.Wait()
+
+	for i := range paths {
+		if errs[i] != nil {
+			t.Fatalf("FetchToCache returned error: %v", errs[i])
+		}
+		if paths[i] != paths[0] {
+			t.Errorf("Expected all fetches to return %q, but got %q", paths[0], paths[i])
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(paths[0], "file"))
+	if err != nil || string(content) != "content" {
+		t.Errorf("Expected the cache entry to contain the fetched file, but got %q, %v", content, err)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DiffKind describes how a path in a destination tree differs from the
+// source tree it was copied from.
+type DiffKind string
+
+const (
+	// DiffAdded means the path only exists in the destination.
+	DiffAdded DiffKind = "added"
+	// DiffRemoved means the path only exists in the source.
+	DiffRemoved DiffKind = "removed"
+	// DiffModified means the path exists in both trees with a different
+	// type, mode, symlink target or content.
+	DiffModified DiffKind = "modified"
+)
+
+type Diff struct {
+	// Path is the slash-separated path relative to the compared roots.
+	Path string   `json:"path"`
+	Kind DiffKind `json:"kind"`
+}
+
+type treeEntry struct {
+	mode   fs.FileMode
+	size   int64
+	target string
+}
+
+// CompareTrees compares the destination tree dst against the source tree
+// src and returns every difference, sorted by path. The comparison mirrors
+// the copy performed by SyncTree: symlinks in src that point outside of src
+// are ignored, and a missing dst is treated as an empty tree.
+func CompareTrees(src string, dst string) ([]Diff, error) {
+	diffs, _, _, err := compareTrees(src, dst)
+	return diffs, err
+}
+
+// compareTrees implements CompareTrees and additionally returns the entries
+// of src and the paths of the symlinks in src that were skipped.
+func compareTrees(src string, dst string) ([]Diff, map[string]treeEntry, []string, error) {
+	srcEntries, skipped, err := listTree(src, true)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dstEntries, _, err := listTree(dst, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var diffs []Diff
+	for name, srcEntry := range srcEntries {
+		dstEntry, ok := dstEntries[name]
+		if !ok {
+			diffs = append(diffs, Diff{Path: name, Kind: DiffRemoved})
+			continue
+		}
+
+		equal, err := equalEntries(filepath.Join(src, name), srcEntry, filepath.Join(dst, name), dstEntry)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !equal {
+			diffs = append(diffs, Diff{Path: name, Kind: DiffModified})
+		}
+	}
+
+	for name := range dstEntries {
+		if _, ok := srcEntries[name]; !ok {
+			diffs = append(diffs, Diff{Path: name, Kind: DiffAdded})
+		}
+	}
+
+	slices.SortFunc(diffs, func(a, b Diff) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	slices.Sort(skipped)
+
+	return diffs, srcEntries, skipped, nil
+}
+
+// listTree lists the entries below root. With skipUnsafeLinks set,
+// symlinks that point outside of root are left out and returned separately.
+func listTree(root string, skipUnsafeLinks bool) (map[string]treeEntry, []string, error) {
+	entries := map[string]treeEntry{}
+	var skipped []string
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		entry := treeEntry{mode: info.Mode()}
+		switch {
+		case info.Mode().IsRegular():
+			entry.size = info.Size()
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			if skipUnsafeLinks && !isSafeLink(rel, target) {
+				skipped = append(skipped, rel)
+				return nil
+			}
+			entry.target = target
+		}
+
+		entries[rel] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, skipped, nil
+}
+
+// isSafeLink reports whether a symlink at rel (relative to the tree root)
+// pointing at target stays inside the tree, using the same rule as rsync's
+// --safe-links, which klone used to copy trees: absolute targets are unsafe,
+// as are relative targets that climb above the root at any point.
+func isSafeLink(rel string, target string) bool {
+	target = filepath.ToSlash(target)
+	if target == "" || path.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return false
+	}
+
+	depth := strings.Count(rel, "/")
+	for _, seg := range strings.Split(target, "/") {
+		switch seg {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return false
+			}
+		default:
+			depth++
+		}
+	}
+
+	return true
+}
+
+// equalEntries reports whether the destination entry dst matches the source
+// entry src.
+func equalEntries(srcPath string, src treeEntry, dstPath string, dst treeEntry) (bool, error) {
+	if src.mode.Type() != dst.mode.Type() {
+		return false, nil
+	}
+
+	switch {
+	case src.mode.IsDir():
+		return src.mode.Perm() == dst.mode.Perm(), nil
+	case src.mode.IsRegular():
+		if src.mode.Perm() != dst.mode.Perm() || src.size != dst.size {
+			return false, nil
+		}
+		return equalFileContents(srcPath, dstPath)
+	case src.mode&fs.ModeSymlink != 0:
+		return src.target == dst.target, nil
+	}
+
+	return true, nil
+}
+
+func equalFileContents(a string, b string) (bool, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+
+	fb, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, 32*1024)
+	bufB := make([]byte, 32*1024)
+	for {
+		nA, errA := io.ReadFull(fa, bufA)
+		nB, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, errA
+		}
+		if errB != nil && !doneB {
+			return false, errB
+		}
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+	}
+}
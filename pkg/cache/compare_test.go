@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestCompareTrees(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	testutil.WriteFiles(t, src, map[string]string{
+		"same.txt":        "same",
+		"changed.txt":     "upstream",
+		"resized.txt":     "upstream",
+		"removed.txt":     "gone",
+		"dir/nested.txt":  "nested",
+		"dir/missing.txt": "missing",
+	})
+	testutil.WriteFiles(t, dst, map[string]string{
+		"same.txt":       "same",
+		"changed.txt":    "upstreaM",
+		"resized.txt":    "local edit",
+		"dir/nested.txt": "nested",
+		"dir/added.txt":  "added",
+		"extra/file.txt": "extra",
+	})
+
+	diffs, err := CompareTrees(src, dst)
+	if err != nil {
+		t.Fatalf("CompareTrees: %v", err)
+	}
+
+	want := []Diff{
+		{Path: "changed.txt", Kind: DiffModified},
+		{Path: "dir/added.txt", Kind: DiffAdded},
+		{Path: "dir/missing.txt", Kind: DiffRemoved},
+		{Path: "extra", Kind: DiffAdded},
+		{Path: "extra/file.txt", Kind: DiffAdded},
+		{Path: "removed.txt", Kind: DiffRemoved},
+		{Path: "resized.txt", Kind: DiffModified},
+	}
+	if !slices.Equal(diffs, want) {
+		t.Errorf("CompareTrees() = %v, want %v", diffs, want)
+	}
+}
+
+func TestCompareTrees_ModeChange(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+	testutil.WriteFiles(t, src, map[string]string{"run.sh": "#!/bin/sh"})
+	testutil.WriteFiles(t, dst, map[string]string{"run.sh": "#!/bin/sh"})
+
+	if err := os.Chmod(filepath.Join(src, "run.sh"), 0o755); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	diffs, err := CompareTrees(src, dst)
+	if err != nil {
+		t.Fatalf("CompareTrees: %v", err)
+	}
+	want := []Diff{{Path: "run.sh", Kind: DiffModified}}
+	if !slices.Equal(diffs, want) {
+		t.Errorf("CompareTrees() = %v, want %v", diffs, want)
+	}
+}
+
+func TestCompareTrees_MissingDestination(t *testing.T) {
+	src := t.TempDir()
+	testutil.WriteFiles(t, src, map[string]string{"a.txt": "a"})
+
+	diffs, err := CompareTrees(src, filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("CompareTrees: %v", err)
+	}
+	want := []Diff{{Path: "a.txt", Kind: DiffRemoved}}
+	if !slices.Equal(diffs, want) {
+		t.Errorf("CompareTrees() = %v, want %v", diffs, want)
+	}
+}
+
+func TestCompareTrees_UnsafeLinksIgnored(t *testing.T) {
+	testutil.SkipIfNoSymlinks(t)
+	src := t.TempDir()
+	dst := t.TempDir()
+	testutil.WriteFiles(t, src, map[string]string{"dir/a.txt": "a"})
+	testutil.WriteFiles(t, dst, map[string]string{"dir/a.txt": "a"})
+
+	if err := os.Symlink("/etc/passwd", filepath.Join(src, "dir", "abs")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if err := os.Symlink("../../outside", filepath.Join(src, "dir", "escape")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(src, "dir", "safe")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	diffs, err := CompareTrees(src, dst)
+	if err != nil {
+		t.Fatalf("CompareTrees: %v", err)
+	}
+	want := []Diff{{Path: "dir/safe", Kind: DiffRemoved}}
+	if !slices.Equal(diffs, want) {
+		t.Errorf("CompareTrees() = %v, want %v", diffs, want)
+	}
+}
+
+func TestIsSafeLink(t *testing.T) {
+	tests := []struct {
+		rel    string
+		target string
+		want   bool
+	}{
+		{rel: "link", target: "file", want: true},
+		{rel: "a/link", target: "../file", want: true},
+		{rel: "a/b/link", target: "../../file", want: true},
+		{rel: "a/link", target: "./b/../c", want: true},
+		{rel: "link", target: "../file", want: false},
+		{rel: "a/link", target: "../../file", want: false},
+		{rel: "a/link", target: "b/../../../file", want: false},
+		{rel: "link", target: "/etc/passwd", want: false},
+		{rel: "link", target: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isSafeLink(tt.rel, tt.target); got != tt.want {
+			t.Errorf("isSafeLink(%q, %q) = %v, want %v", tt.rel, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// validateGlobs returns an error if any of the patterns is malformed.
+func validateGlobs(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, seg := range strings.Split(pattern, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the slash-separated name matches pattern. Each
+// pattern segment is matched with path.Match, except for "**" which matches
+// any number of segments (including none).
+func matchGlob(pattern string, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// matchAny reports whether name, or any of its parent directories, matches
+// one of the patterns, so that a pattern naming a directory applies to all
+// of its contents.
+func matchAny(patterns []string, name string) bool {
+	for i := range len(name) {
+		if name[i] == '/' && slices.ContainsFunc(patterns, func(p string) bool { return matchGlob(p, name[:i]) }) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(patterns, func(p string) bool { return matchGlob(p, name) })
+}
+
+// filterTree removes every file below root that does not match one of the
+// include patterns (if there are any) or that matches one of the exclude
+// patterns. Directories that are left empty are removed as well.
+func filterTree(root string, include []string, exclude []string) error {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	if err := validateGlobs(include); err != nil {
+		return err
+	}
+	if err := validateGlobs(exclude); err != nil {
+		return err
+	}
+
+	var dirs []string
+	if err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(exclude, rel) {
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, p)
+			return nil
+		}
+
+		if len(include) > 0 && !matchAny(include, rel) {
+			return os.Remove(p)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// remove empty directories, deepest first
+	for _, dir := range slices.Backward(dirs) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
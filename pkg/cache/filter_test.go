@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "README.md", name: "README.md", want: true},
+		{pattern: "*.md", name: "README.md", want: true},
+		{pattern: "*.md", name: "docs/README.md", want: false},
+		{pattern: "**/*.md", name: "README.md", want: true},
+		{pattern: "**/*.md", name: "docs/a/README.md", want: true},
+		{pattern: "docs/**", name: "docs", want: true},
+		{pattern: "docs/**", name: "docs/a/b.txt", want: true},
+		{pattern: "a/**/z.txt", name: "a/z.txt", want: true},
+		{pattern: "a/**/z.txt", name: "a/b/c/z.txt", want: true},
+		{pattern: "a/**/z.txt", name: "b/z.txt", want: false},
+		{pattern: "test?", name: "tests", want: true},
+		{pattern: "[abc].go", name: "b.go", want: true},
+		{pattern: "a/b", name: "a", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func listFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	if err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel != "." {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	return files
+}
+
+func TestFilterTree(t *testing.T) {
+	files := map[string]string{
+		"01_mod.mk":              "",
+		"README.md":              "",
+		"base/.github/ci.yaml":   "",
+		"base/Makefile":          "",
+		"testdata/fixture.yaml":  "",
+		"testdata/sub/more.yaml": "",
+		"tools/tools.mk":         "",
+		"tools/tools_test.go":    "",
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name: "no filters",
+			want: []string{"01_mod.mk", "README.md", "base", "base/.github", "base/.github/ci.yaml", "base/Makefile", "testdata", "testdata/fixture.yaml", "testdata/sub", "testdata/sub/more.yaml", "tools", "tools/tools.mk", "tools/tools_test.go"},
+		},
+		{
+			name:    "exclude directory and pattern",
+			exclude: []string{"testdata", "**/*_test.go", "*.md"},
+			want:    []string{"01_mod.mk", "base", "base/.github", "base/.github/ci.yaml", "base/Makefile", "tools", "tools/tools.mk"},
+		},
+		{
+			name:    "include only",
+			include: []string{"**/*.mk", "base"},
+			want:    []string{"01_mod.mk", "base", "base/.github", "base/.github/ci.yaml", "base/Makefile", "tools", "tools/tools.mk"},
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{"base/**"},
+			exclude: []string{"base/.github"},
+			want:    []string{"base", "base/Makefile"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			testutil.WriteFiles(t, root, files)
+
+			if err := filterTree(root, tt.include, tt.exclude); err != nil {
+				t.Fatalf("filterTree: %v", err)
+			}
+
+			if got := listFiles(t, root); !slices.Equal(got, tt.want) {
+				t.Errorf("filterTree left %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTree_InvalidPattern(t *testing.T) {
+	if err := filterTree(t.TempDir(), []string{"[a-"}, nil); err == nil {
+		t.Errorf("filterTree with a malformed pattern returned nil, want error")
+	}
+}
+
+func TestCalculateCacheKey_Filters(t *testing.T) {
+	src := mod.KloneSource{
+		RepoURL:  "https://github.com/cert-manager/makefile-modules.git",
+		RepoHash: "c9f456aba467621bc7836179b91b99e0fe0a3f21",
+		RepoPath: "modules/go",
+	}
+
+	// The key of an unfiltered item must not change, so that existing cache
+	// entries keep being used.
+	if got, want := calculateCacheKey(src), "cache-27fd7bcf23b7539c96f225ba"; got != want {
+		t.Errorf("calculateCacheKey(unfiltered) = %q, want %q", got, want)
+	}
+
+	included := src
+	included.Include = []string{"*.mk"}
+	excluded := src
+	excluded.Exclude = []string{"*.mk"}
+
+	keys := []string{calculateCacheKey(src), calculateCacheKey(included), calculateCacheKey(excluded)}
+	if keys[0] == keys[1] || keys[0] == keys[2] || keys[1] == keys[2] {
+		t.Errorf("differently filtered items share a cache key: %v", keys)
+	}
+}
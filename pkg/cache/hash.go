@@ -0,0 +1,104 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// ErrContentHashMismatch is returned when the content of a cache entry does
+// not match the content hash it was pinned with.
+var ErrContentHashMismatch = errors.New("content hash mismatch")
+
+// HashTree returns a deterministic hash of the files below root, in the
+// "h1:" format of golang.org/x/mod/sumdb/dirhash. Symlinks are included and
+// hashed by their target with a "symlink\x00" prefix, so that they never hash
+// like a file with the same content; directories and file permissions are
+// not part of the hash.
+func HashTree(root string) (string, error) {
+	entries, _, err := listTree(root, false)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		if entries[name].mode.IsDir() {
+			continue
+		}
+		files = append(files, name)
+	}
+
+	return dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		entry := entries[name]
+		if entry.mode&fs.ModeSymlink != 0 {
+			return io.NopCloser(strings.NewReader("symlink\x00" + entry.target)), nil
+		}
+		return os.Open(filepath.Join(root, filepath.FromSlash(name)))
+	})
+}
+
+// HashFiles returns the sha256 of every file below root, keyed by its slash
+// separated path relative to root. Symlinks are hashed by their target and
+// directories are skipped. A missing root has no files.
+func HashFiles(root string) (map[string]string, error) {
+	entries, _, err := listTree(root, false)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string, len(entries))
+	for name, entry := range entries {
+		hash := sha256.New()
+		switch {
+		case entry.mode.IsDir():
+			continue
+		case entry.mode&fs.ModeSymlink != 0:
+			// a symlink never hashes like a file with the same content
+			io.WriteString(hash, "symlink\x00"+entry.target)
+		default:
+			if err := hashFile(hash, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+				return nil, err
+			}
+		}
+		hashes[name] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return hashes, nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
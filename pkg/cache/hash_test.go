@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestHashTree(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteFiles(t, root, map[string]string{
+		"a.txt":     "a",
+		"dir/b.txt": "b",
+	})
+
+	hash, err := HashTree(root)
+	if err != nil {
+		t.Fatalf("HashTree: %v", err)
+	}
+
+	// without symlinks, the hash is the same as Go's module hash
+	want, err := dirhash.HashDir(root, "", dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != want {
+		t.Errorf("HashTree() = %s, want %s", hash, want)
+	}
+
+	testutil.SkipIfNoSymlinks(t)
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	withLink, err := HashTree(root)
+	if err != nil {
+		t.Fatalf("HashTree: %v", err)
+	}
+	if withLink == hash {
+		t.Errorf("Expected a new symlink to change the hash")
+	}
+
+	if err := os.Remove(filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("dir/b.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	retargeted, err := HashTree(root)
+	if err != nil {
+		t.Fatalf("HashTree: %v", err)
+	}
+	if retargeted == withLink {
+		t.Errorf("Expected a changed symlink target to change the hash")
+	}
+
+	// a symlink never hashes like a file containing its target
+	if err := os.Remove(filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFiles(t, root, map[string]string{"link": "dir/b.txt"})
+	asFile, err := HashTree(root)
+	if err != nil {
+		t.Fatalf("HashTree: %v", err)
+	}
+	if asFile == retargeted {
+		t.Errorf("Expected a symlink and a file with the same content to differ")
+	}
+}
+
+func TestFetchToCache_ContentHash(t *testing.T) {
+	t.Setenv("KLONE_CACHE_DIR", t.TempDir())
+
+	content := "upstream"
+	getFn := func(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
+		outPath := filepath.Join(targetPath, src.RepoPath)
+		if err := os.MkdirAll(outPath, 0o755); err != nil {
+			return "", err
+		}
+		return outPath, os.WriteFile(filepath.Join(outPath, "file"), []byte(content), 0o644)
+	}
+
+	src := mod.KloneSource{RepoURL: "https://github.com/repo", RepoHash: "abc123", RepoPath: "path"}
+	cachePath, err := FetchToCache(t.Context(), src, getFn)
+	if err != nil {
+		t.Fatalf("FetchToCache: %v", err)
+	}
+	src.ContentHash, err = HashTree(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a matching cache entry is used
+	if _, err := FetchToCache(t.Context(), src, getFn); err != nil {
+		t.Errorf("FetchToCache with matching content hash: %v", err)
+	}
+
+	// a tampered cache entry is rejected
+	if err := os.WriteFile(filepath.Join(cachePath, "file"), []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FetchToCache(t.Context(), src, getFn); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("Expected ErrContentHashMismatch for a tampered cache entry, but got %v", err)
+	}
+
+	// content served for a new cache entry that does not match is not cached
+	if err := os.RemoveAll(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	content = "compromised mirror"
+	if _, err := FetchToCache(t.Context(), src, getFn); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("Expected ErrContentHashMismatch for mismatching upstream content, but got %v", err)
+	}
+	if _, exists, err := Lookup(t.Context(), src); err != nil || exists {
+		t.Errorf("Expected mismatching content not to be cached, but got %v, %v", exists, err)
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteFiles(t, root, map[string]string{
+		"a.txt":     "a",
+		"dir/b.txt": "b",
+		"link.txt":  "a.txt",
+	})
+	if err := os.Remove(filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("skip: symlinks not supported: %v", err)
+	}
+
+	hashes, err := HashFiles(root)
+	if err != nil {
+		t.Fatalf("HashFiles: %v", err)
+	}
+	if len(hashes) != 3 || hashes["a.txt"] == "" || hashes["dir/b.txt"] == "" || hashes["link.txt"] == "" {
+		t.Fatalf("Expected hashes for all files, but got %v", hashes)
+	}
+
+	// a symlink never hashes like a file containing its target
+	testutil.WriteFiles(t, root, map[string]string{"target.txt": "a.txt"})
+	if hashes, err = HashFiles(root); err != nil || hashes["link.txt"] == hashes["target.txt"] {
+		t.Errorf("Expected a symlink and a file with the same content to differ, but got %v, %v", hashes, err)
+	}
+
+	if hashes, err := HashFiles(filepath.Join(root, "missing")); err != nil || len(hashes) != 0 {
+		t.Errorf("Expected a missing root to have no files, but got %v, %v", hashes, err)
+	}
+}
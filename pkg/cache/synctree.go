@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/cert-manager/klone/pkg/logging"
+)
+
+// SyncTree makes destPath an exact copy of srcPath and returns the
+// differences it removed, as reported by CompareTrees before the copy.
+//
+// Extraneous files in destPath are deleted and only files whose type,
+// permissions or content differ are rewritten. Symlinks are never followed:
+// a symlink in srcPath is copied as a symlink if its target stays inside
+// srcPath, and skipped with a warning otherwise. Symlinks in destPath are
+// replaced, never written through.
+func SyncTree(ctx context.Context, srcPath string, destPath string) ([]Diff, error) {
+	diffs, srcEntries, skipped, err := compareTrees(srcPath, destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range skipped {
+		logging.FromContext(ctx).Warn("skipping symlink, it points outside of the kloned folder", "path", link, "dir", srcPath)
+	}
+
+	if err := os.MkdirAll(destPath, 0o755); err != nil {
+		return nil, err
+	}
+
+	// 1) remove everything that is extraneous or has a different type, in
+	// reverse order so that children are removed before their parents
+	for _, diff := range slices.Backward(diffs) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		dst := filepath.Join(destPath, filepath.FromSlash(diff.Path))
+		switch diff.Kind {
+		case DiffAdded:
+			if err := os.RemoveAll(dst); err != nil {
+				return nil, err
+			}
+		case DiffModified:
+			info, err := os.Lstat(dst)
+			if err != nil {
+				return nil, err
+			}
+			if info.Mode().Type() != srcEntries[diff.Path].mode.Type() {
+				if err := os.RemoveAll(dst); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// 2) create or rewrite everything that is missing or differs, in order
+	// so that parents are created before their children
+	for _, diff := range diffs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if diff.Kind == DiffAdded {
+			continue
+		}
+
+		src := filepath.Join(srcPath, filepath.FromSlash(diff.Path))
+		dst := filepath.Join(destPath, filepath.FromSlash(diff.Path))
+		if err := copyEntry(src, srcEntries[diff.Path], dst); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3) set the permissions of directories last, in reverse order, so that
+	// read-only directories can still be filled
+	for _, diff := range slices.Backward(diffs) {
+		if entry := srcEntries[diff.Path]; diff.Kind != DiffAdded && entry.mode.IsDir() {
+			dst := filepath.Join(destPath, filepath.FromSlash(diff.Path))
+			if err := os.Chmod(dst, entry.mode.Perm()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return diffs, nil
+}
+
+// copyEntry creates dst as a copy of the entry src, replacing an existing
+// entry of the same type.
+func copyEntry(src string, entry treeEntry, dst string) error {
+	switch {
+	case entry.mode.IsDir():
+		// the permissions are set by SyncTree once the directory is filled
+		if err := os.Mkdir(dst, 0o700); err != nil && !os.IsExist(err) {
+			return err
+		}
+		return nil
+	case entry.mode&fs.ModeSymlink != 0:
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return os.Symlink(entry.target, dst)
+	case entry.mode.IsRegular():
+		return copyFile(src, entry.mode.Perm(), dst)
+	default:
+		return fmt.Errorf("cannot copy %s: unsupported file type %s", src, entry.mode.Type())
+	}
+}
+
+// copyFile writes the contents of src to a temporary file next to dst and
+// renames it over dst, so that dst is never partially written and a
+// symlink at dst is replaced instead of followed.
+func copyFile(src string, perm fs.FileMode, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), ".klone-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(out.Name())
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Chmod(perm); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(out.Name(), dst)
+}
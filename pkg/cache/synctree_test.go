@@ -0,0 +1,202 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestSyncTree(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "dst")
+
+	testutil.WriteFiles(t, src, map[string]string{
+		"same.txt":        "same",
+		"changed.txt":     "upstream",
+		"new.txt":         "new",
+		"dir/nested.txt":  "nested",
+		"typechange/file": "now a directory",
+		"run.sh":          "#!/bin/sh",
+	})
+	if err := os.Chmod(filepath.Join(src, "run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	testutil.WriteFiles(t, dst, map[string]string{
+		"same.txt":       "same",
+		"changed.txt":    "local edit",
+		"dir/nested.txt": "nested",
+		"dir/extra.txt":  "extra",
+		"extra/file.txt": "extra",
+		"typechange":     "was a file",
+		"run.sh":         "#!/bin/sh",
+	})
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dst, "same.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := SyncTree(t.Context(), src, dst)
+	if err != nil {
+		t.Fatalf("SyncTree: %v", err)
+	}
+
+	want := []Diff{
+		{Path: "changed.txt", Kind: DiffModified},
+		{Path: "dir/extra.txt", Kind: DiffAdded},
+		{Path: "extra", Kind: DiffAdded},
+		{Path: "extra/file.txt", Kind: DiffAdded},
+		{Path: "new.txt", Kind: DiffRemoved},
+		{Path: "run.sh", Kind: DiffModified},
+		{Path: "typechange", Kind: DiffModified},
+		{Path: "typechange/file", Kind: DiffRemoved},
+	}
+	if !slices.Equal(diffs, want) {
+		t.Errorf("SyncTree() = %v, want %v", diffs, want)
+	}
+
+	if remaining, err := CompareTrees(src, dst); err != nil || len(remaining) != 0 {
+		t.Errorf("Expected the trees to be equal after SyncTree, but got %v, %v", remaining, err)
+	}
+
+	info, err := os.Stat(filepath.Join(dst, "same.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("Expected unchanged files not to be rewritten, but same.txt was modified at %v", info.ModTime())
+	}
+
+	if again, err := SyncTree(t.Context(), src, dst); err != nil || len(again) != 0 {
+		t.Errorf("Expected a second SyncTree to change nothing, but got %v, %v", again, err)
+	}
+}
+
+func TestSyncTree_DirectoryModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directory permissions are not supported on windows")
+	}
+
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "dst")
+
+	testutil.WriteFiles(t, src, map[string]string{
+		"private/file.txt":  "private",
+		"readonly/file.txt": "read-only",
+	})
+	chmod := func(path string, perm os.FileMode) {
+		t.Helper()
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chmod(filepath.Join(src, "private"), 0o755)
+	chmod(filepath.Join(src, "readonly"), 0o555)
+	t.Cleanup(func() { os.Chmod(filepath.Join(dst, "readonly"), 0o755) })
+
+	if _, err := SyncTree(t.Context(), src, dst); err != nil {
+		t.Fatalf("SyncTree: %v", err)
+	}
+
+	// the upstream mode of an existing directory changes between syncs
+	chmod(filepath.Join(src, "private"), 0o700)
+
+	diffs, err := SyncTree(t.Context(), src, dst)
+	if err != nil {
+		t.Fatalf("SyncTree: %v", err)
+	}
+	if want := []Diff{{Path: "private", Kind: DiffModified}}; !slices.Equal(diffs, want) {
+		t.Errorf("SyncTree() = %v, want %v", diffs, want)
+	}
+
+	for dir, want := range map[string]os.FileMode{"private": 0o700, "readonly": 0o555} {
+		info, err := os.Stat(filepath.Join(dst, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("Mode of %s = %v, want %v", dir, info.Mode().Perm(), want)
+		}
+	}
+
+	if again, err := SyncTree(t.Context(), src, dst); err != nil || len(again) != 0 {
+		t.Errorf("Expected a second SyncTree to change nothing, but got %v, %v", again, err)
+	}
+}
+
+func TestSyncTree_Symlinks(t *testing.T) {
+	testutil.SkipIfNoSymlinks(t)
+
+	src := t.TempDir()
+	dst := t.TempDir()
+	outside := t.TempDir()
+
+	testutil.WriteFiles(t, src, map[string]string{
+		"dir/file.txt": "upstream",
+		"file.txt":     "upstream",
+	})
+	for name, target := range map[string]string{
+		"safe":     "dir/file.txt",
+		"absolute": "/etc/passwd",
+		"escape":   "../outside",
+	} {
+		if err := os.Symlink(target, filepath.Join(src, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// symlinks in the destination must be replaced, not written through
+	testutil.WriteFiles(t, outside, map[string]string{"file.txt": "outside"})
+	if err := os.Symlink(filepath.Join(outside, "file.txt"), filepath.Join(dst, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dst, "dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SyncTree(t.Context(), src, dst); err != nil {
+		t.Fatalf("SyncTree: %v", err)
+	}
+
+	if target, err := os.Readlink(filepath.Join(dst, "safe")); err != nil || target != "dir/file.txt" {
+		t.Errorf("Expected the safe symlink to be copied, but got %q, %v", target, err)
+	}
+	for _, name := range []string{"absolute", "escape"} {
+		if _, err := os.Lstat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected the unsafe symlink %s to be skipped, but got %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"file.txt", "dir"} {
+		info, err := os.Lstat(filepath.Join(dst, name))
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			t.Errorf("Expected %s to be replaced by a regular entry, but got %v, %v", name, info, err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(outside, "file.txt"))
+	if err != nil || string(content) != "outside" {
+		t.Errorf("Expected the file outside of the destination to be untouched, but got %q, %v", content, err)
+	}
+}
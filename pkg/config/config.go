@@ -0,0 +1,615 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config reads the global klone settings from the klone config file
+// and the environment.
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv names a config file to use instead of the default one. Unlike
+// the default config file, it must exist.
+const ConfigEnv = "KLONE_CONFIG"
+
+const (
+	// GitBackendExec runs the git binary.
+	GitBackendExec = "exec"
+	// GitBackendGoGit talks to remotes in-process and does not need a git
+	// binary. Unlike git, it cannot filter out file contents and fetches
+	// the full history of all branches and tags from servers that do not
+	// allow fetching a commit that no ref points to.
+	GitBackendGoGit = "go-git"
+)
+
+// OriginDefault is the origin of settings that are not configured.
+const OriginDefault = "default"
+
+// Config holds the global settings of klone. Every setting is read from
+// the config file and can be overridden by an env var.
+type Config struct {
+	// Path is the config file that was read, it does not have to exist. It
+	// is empty if KLONE_CONFIG is not set and there is no home directory.
+	Path string `yaml:"-"`
+
+	// CacheDir is the directory of the klone cache. Relative paths in the
+	// config file are resolved against the directory of the config file.
+	CacheDir string `yaml:"cache_dir"`
+	// CacheTTL is how long a cache entry is kept after it was last written.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// RetryAttempts is how many times a git command that accesses the
+	// network is attempted.
+	RetryAttempts int `yaml:"retry_attempts"`
+	// Jobs is the default number of items that are resolved and downloaded
+	// concurrently.
+	Jobs int `yaml:"jobs"`
+	// GitBackend is GitBackendExec or GitBackendGoGit. If it is empty, the
+	// git binary is used if it is available.
+	GitBackend string `yaml:"git_backend"`
+	// Mirrors rewrite the URLs of git repositories before they are fetched.
+	Mirrors []Mirror `yaml:"mirrors"`
+	// Credentials authenticate klone to the hosts of private git
+	// repositories. Relative paths are resolved like CacheDir.
+	Credentials []Credential `yaml:"credentials"`
+
+	origins map[string]string
+	errs    map[string]error
+}
+
+// Mirror replaces the prefix InsteadOf of a git repository URL with URL,
+// like the "url.<base>.insteadOf" setting of git. The URL in klone.yaml, and
+// the cache key, stay the same.
+type Mirror struct {
+	URL       string `yaml:"url" json:"url"`
+	InsteadOf string `yaml:"instead_of" json:"instead_of"`
+}
+
+// Validate checks that both URL and InsteadOf are set.
+func (m Mirror) Validate() error {
+	if m.URL == "" || m.InsteadOf == "" {
+		return fmt.Errorf("mirror %q instead of %q: url and instead_of must be set", RedactURL(m.URL), RedactURL(m.InsteadOf))
+	}
+	return nil
+}
+
+// RedactURL replaces the password of a URL, or the username of an http(s)
+// URL without password, which is where tokens are passed, with "xxxxx". It
+// makes sure that secrets in a repo_url or mirror URL are never logged or
+// printed. Other strings are returned unchanged.
+func RedactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+
+	if _, ok := u.User.Password(); ok {
+		return u.Redacted()
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		u.User = url.User("xxxxx")
+	}
+	return u.String()
+}
+
+// DefaultUsername is the username that is sent with a token if no username
+// is configured. GitHub and most other hosts accept any username.
+const DefaultUsername = "x-access-token"
+
+// Credential authenticates klone to the git remotes on Host: the token is
+// used for http and https remotes, the SSH key for ssh remotes. The secrets
+// themselves are never stored in the config file.
+type Credential struct {
+	// Host is the host name of the remotes, without port.
+	Host string `yaml:"host"`
+	// Username is sent with the token, DefaultUsername if it is empty.
+	Username string `yaml:"username,omitempty"`
+	// TokenEnv is the env var that contains the token.
+	TokenEnv string `yaml:"token_env,omitempty"`
+	// TokenFile is the file that contains the token.
+	TokenFile string `yaml:"token_file,omitempty"`
+	// SSHKey is the private key file used for ssh remotes.
+	SSHKey string `yaml:"ssh_key,omitempty"`
+}
+
+// Validate checks that Host and at most one token source are set, and
+// that the credential has either a token source or an SSH key.
+func (c Credential) Validate() error {
+	switch {
+	case c.Host == "":
+		return errors.New("credential without host")
+	case c.TokenEnv != "" && c.TokenFile != "":
+		return fmt.Errorf("credential for %s: only one of token_env and token_file may be set", c.Host)
+	case c.TokenEnv == "" && c.TokenFile == "" && c.SSHKey == "":
+		return fmt.Errorf("credential for %s: one of token_env, token_file or ssh_key must be set", c.Host)
+	}
+	return nil
+}
+
+// Token reads the token from TokenEnv or TokenFile. It returns an empty
+// string if the credential has no token source.
+func (c Credential) Token() (string, error) {
+	switch {
+	case c.TokenEnv != "":
+		token := os.Getenv(c.TokenEnv)
+		if token == "" {
+			return "", fmt.Errorf("the token for %s in %s is empty", c.Host, c.TokenEnv)
+		}
+		return token, nil
+	case c.TokenFile != "":
+		data, err := os.ReadFile(c.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the token for %s: %w", c.Host, err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return "", fmt.Errorf("the token for %s in %s is empty", c.Host, c.TokenFile)
+		}
+		return token, nil
+	}
+	return "", nil
+}
+
+// String describes the credential without revealing any secret.
+func (c Credential) String() string {
+	var sources []string
+	for _, source := range [][2]string{
+		{"username", c.Username},
+		{"token_env", c.TokenEnv},
+		{"token_file", c.TokenFile},
+		{"ssh_key", c.SSHKey},
+	} {
+		if source[1] != "" {
+			sources = append(sources, source[0]+"="+source[1])
+		}
+	}
+	return c.Host + "(" + strings.Join(sources, " ") + ")"
+}
+
+// CredentialFor returns the credential for host, which is compared
+// case-insensitively.
+func (c *Config) CredentialFor(host string) (Credential, bool) {
+	for _, credential := range c.Credentials {
+		if strings.EqualFold(credential.Host, host) {
+			return credential, true
+		}
+	}
+	return Credential{}, false
+}
+
+// Setting is a single effective setting and where it was configured.
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Origin is OriginDefault, the path of the config file, or "env "
+	// followed by the name of the env var.
+	Origin string `json:"origin"`
+	// Error is why the setting is invalid, if it is.
+	Error string `json:"error,omitempty"`
+}
+
+// setting describes how a setting of Config is read from the environment
+// and validated. Settings without env can only be set in the config file.
+type setting struct {
+	name   string
+	env    string
+	parse  func(c *Config, value string) error
+	format func(c *Config) string
+	check  func(c *Config) error
+}
+
+var settings = []setting{
+	{
+		name: "cache_dir",
+		env:  "KLONE_CACHE_DIR",
+		parse: func(c *Config, value string) error {
+			// relative to the working directory, unlike in the config file
+			dir, err := filepath.Abs(value)
+			c.CacheDir = dir
+			return err
+		},
+		format: func(c *Config) string { return c.CacheDir },
+		check: func(c *Config) error {
+			if !filepath.IsAbs(c.CacheDir) {
+				return errors.New("must be an absolute path")
+			}
+			return nil
+		},
+	},
+	{
+		name: "cache_ttl",
+		env:  "KLONE_CACHE_TTL",
+		parse: func(c *Config, value string) (err error) {
+			c.CacheTTL, err = time.ParseDuration(value)
+			return err
+		},
+		format: func(c *Config) string { return c.CacheTTL.String() },
+		check: func(c *Config) error {
+			if c.CacheTTL <= 0 {
+				return errors.New("must be positive")
+			}
+			return nil
+		},
+	},
+	{
+		name: "retry_attempts",
+		env:  "KLONE_GIT_RETRY_ATTEMPTS",
+		parse: func(c *Config, value string) (err error) {
+			c.RetryAttempts, err = strconv.Atoi(value)
+			return err
+		},
+		format: func(c *Config) string { return strconv.Itoa(c.RetryAttempts) },
+		check: func(c *Config) error {
+			if c.RetryAttempts < 1 {
+				return errors.New("must be at least 1")
+			}
+			return nil
+		},
+	},
+	{
+		name: "jobs",
+		env:  "KLONE_JOBS",
+		parse: func(c *Config, value string) (err error) {
+			c.Jobs, err = strconv.Atoi(value)
+			return err
+		},
+		format: func(c *Config) string { return strconv.Itoa(c.Jobs) },
+		check: func(c *Config) error {
+			if c.Jobs < 1 {
+				return errors.New("must be at least 1")
+			}
+			return nil
+		},
+	},
+	{
+		name: "git_backend",
+		env:  "KLONE_GIT_BACKEND",
+		parse: func(c *Config, value string) error {
+			c.GitBackend = value
+			return nil
+		},
+		format: func(c *Config) string { return c.GitBackend },
+		check: func(c *Config) error {
+			switch c.GitBackend {
+			case "", GitBackendExec, GitBackendGoGit:
+				return nil
+			}
+			return fmt.Errorf("must be %q or %q", GitBackendExec, GitBackendGoGit)
+		},
+	},
+	{
+		name: "mirrors",
+		// a comma separated list of instead_of=url pairs
+		env: "KLONE_MIRRORS",
+		parse: func(c *Config, value string) error {
+			c.Mirrors = nil
+			for pair := range strings.SplitSeq(value, ",") {
+				insteadOf, url, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					return fmt.Errorf("%q is not an instead_of=url pair", pair)
+				}
+				c.Mirrors = append(c.Mirrors, Mirror{URL: url, InsteadOf: insteadOf})
+			}
+			return nil
+		},
+		format: func(c *Config) string {
+			pairs := make([]string, len(c.Mirrors))
+			for i, mirror := range c.Mirrors {
+				pairs[i] = RedactURL(mirror.InsteadOf) + "=" + RedactURL(mirror.URL)
+			}
+			return strings.Join(pairs, ",")
+		},
+		check: func(c *Config) error {
+			for _, mirror := range c.Mirrors {
+				if err := mirror.Validate(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		name: "credentials",
+		format: func(c *Config) string {
+			credentials := make([]string, len(c.Credentials))
+			for i, credential := range c.Credentials {
+				credentials[i] = credential.String()
+			}
+			return strings.Join(credentials, ",")
+		},
+		check: func(c *Config) error {
+			hosts := map[string]bool{}
+			for _, credential := range c.Credentials {
+				if err := credential.Validate(); err != nil {
+					return err
+				}
+				host := strings.ToLower(credential.Host)
+				if hosts[host] {
+					return fmt.Errorf("more than one credential for %s", credential.Host)
+				}
+				hosts[host] = true
+			}
+			return nil
+		},
+	},
+}
+
+type contextKey struct{}
+
+// IntoContext returns a copy of ctx that carries cfg, so that every
+// operation of a command uses the same config.
+func IntoContext(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, contextKey{}, cfg)
+}
+
+// FromContext returns the config carried by ctx, or loads it if ctx does
+// not carry one.
+func FromContext(ctx context.Context) (*Config, error) {
+	if cfg, ok := ctx.Value(contextKey{}).(*Config); ok {
+		return cfg, nil
+	}
+	return Load()
+}
+
+// Load reads the config file and applies the env vars on top of it. The
+// config file is named by KLONE_CONFIG, or else is config.yaml in the klone
+// directory of $XDG_CONFIG_HOME (~/.config by default), and may be missing.
+// Load fails on the first invalid setting, Inspect reports all of them.
+func Load() (*Config, error) {
+	c, err := Inspect()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range settings {
+		if err := c.errs[s.name]; err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Inspect is like Load, but does not fail on invalid settings: their errors
+// are reported by Settings instead. It only fails if the config file cannot
+// be read or parsed, and even then returns the config of the defaults and
+// the env vars, so that it can be shown.
+func Inspect() (*Config, error) {
+	// The home directory is only looked up if a default path needs it, so
+	// that klone works without one if KLONE_CONFIG and KLONE_CACHE_DIR are
+	// set.
+	home := sync.OnceValues(os.UserHomeDir)
+
+	c := newConfig()
+	path := os.Getenv(ConfigEnv)
+	explicit := path != ""
+	if !explicit {
+		// Without a home directory, there is no default config file.
+		if dir, err := xdgDir("XDG_CONFIG_HOME", home, ".config"); err == nil {
+			path = filepath.Join(dir, "klone", "config.yaml")
+		}
+	}
+
+	var fileErr error
+	if path != "" {
+		c.Path, fileErr = filepath.Abs(path)
+	}
+	if fileErr == nil && c.Path != "" {
+		data, err := os.ReadFile(c.Path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			fileErr = fmt.Errorf("failed to read the klone config: %w", err)
+		default:
+			if err := c.decode(data, home); err != nil {
+				fileErr = fmt.Errorf("failed to parse %s: %w", c.Path, err)
+			}
+		}
+	}
+	if fileErr != nil {
+		// drop the settings that were decoded before the error
+		c = newConfig()
+		c.Path = path
+	}
+
+	for _, s := range settings {
+		if s.env == "" {
+			continue
+		}
+		value := os.Getenv(s.env)
+		if value == "" {
+			continue
+		}
+		if err := s.parse(c, value); err != nil {
+			c.errs[s.name] = fmt.Errorf("invalid %s %q in %s: %w", s.name, value, s.env, err)
+			continue
+		}
+		c.origins[s.name] = "env " + s.env
+	}
+
+	if c.CacheDir == "" {
+		dir, err := defaultCacheDir(home)
+		if err != nil {
+			c.errs["cache_dir"] = fmt.Errorf("no cache_dir is configured and there is no default: %w", err)
+		}
+		c.CacheDir = dir
+	}
+
+	for _, s := range settings {
+		if c.errs[s.name] != nil {
+			continue
+		}
+		if err := s.check(c); err != nil {
+			c.errs[s.name] = fmt.Errorf("invalid %s %q from %s: %w", s.name, s.format(c), c.origin(s.name), err)
+		}
+	}
+
+	return c, fileErr
+}
+
+// newConfig returns the config with the default settings, except for the
+// cache directory, which depends on the home directory.
+func newConfig() *Config {
+	return &Config{
+		CacheTTL:      7 * 24 * time.Hour,
+		RetryAttempts: 1,
+		Jobs:          4,
+		origins:       map[string]string{},
+		errs:          map[string]error{},
+	}
+}
+
+// decode sets the settings that are present in the config file data.
+func (c *Config) decode(data []byte, home func() (string, error)) error {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	if len(node.Content) == 0 {
+		// empty file
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return err
+	}
+
+	if root := node.Content[0]; root.Kind == yaml.MappingNode {
+		for i := 0; i < len(root.Content); i += 2 {
+			c.origins[root.Content[i].Value] = c.Path
+		}
+	}
+
+	var err error
+	if c.origins["cache_dir"] != "" {
+		if c.CacheDir == "" {
+			return errors.New("cache_dir must not be empty")
+		}
+		if c.CacheDir, err = c.resolvePath(c.CacheDir, home); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Credentials {
+		credential := &c.Credentials[i]
+		if credential.TokenFile != "" {
+			if credential.TokenFile, err = c.resolvePath(credential.TokenFile, home); err != nil {
+				return err
+			}
+		}
+		if credential.SSHKey != "" {
+			if credential.SSHKey, err = c.resolvePath(credential.SSHKey, home); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolvePath expands a leading "~/" of a path in the config file to home
+// and resolves relative paths against the directory of the config file.
+func (c *Config) resolvePath(path string, home func() (string, error)) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		dir, err := home()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %q: %w", path, err)
+		}
+		return filepath.Join(dir, rest), nil
+	}
+	if !filepath.IsAbs(path) {
+		return filepath.Join(filepath.Dir(c.Path), path), nil
+	}
+	return path, nil
+}
+
+func (c *Config) origin(name string) string {
+	if origin, ok := c.origins[name]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Settings returns every effective setting, in a stable order.
+func (c *Config) Settings() []Setting {
+	list := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		setting := Setting{Name: s.name, Value: s.format(c), Origin: c.origin(s.name)}
+		if err := c.errs[s.name]; err != nil {
+			setting.Error = err.Error()
+		}
+		list = append(list, setting)
+	}
+	return list
+}
+
+// defaultCacheDir returns the klone directory in $XDG_CACHE_HOME (~/.cache
+// by default). Before klone read XDG_CACHE_HOME, the cache was always in
+// ~/.cache/klone, which stays in use while it exists and the new directory
+// does not, so that setting XDG_CACHE_HOME does not discard the cache.
+func defaultCacheDir(home func() (string, error)) (string, error) {
+	base, err := xdgDir("XDG_CACHE_HOME", home, ".cache")
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "klone")
+
+	homeDir, err := home()
+	if err != nil {
+		return dir, nil
+	}
+	legacy := filepath.Join(homeDir, ".cache", "klone")
+	if legacy == dir || exists(dir) || !exists(legacy) {
+		return dir, nil
+	}
+	return legacy, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// xdgDir returns the directory in the env var, which the XDG base directory
+// specification requires to be absolute, or else fallback in home.
+func xdgDir(env string, home func() (string, error), fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	dir, err := home()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fallback), nil
+}
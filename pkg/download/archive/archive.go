@@ -0,0 +1,157 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/logging"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// Get downloads the archive src.ArchiveURL, verifies it against src.SHA256,
+// unpacks it to targetPath and returns the path of src.RepoPath in it.
+func Get(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
+	if err := validateSource(src); err != nil {
+		return "", err
+	}
+
+	format, err := formatOf(src.ArchiveURL)
+	if err != nil {
+		return "", err
+	}
+
+	logging.FromContext(ctx).Info("downloading", "archive_url", config.RedactURL(src.ArchiveURL), "repo_path", src.RepoPath, "sha256", src.SHA256, "dir", targetPath)
+
+	file, err := os.CreateTemp("", "klone-archive-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := download(ctx, src.ArchiveURL, src.SHA256, file); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(targetPath, 0o755); err != nil {
+		return "", err
+	}
+
+	if err := unpack(format, file, targetPath, src.StripComponents); err != nil {
+		return "", fmt.Errorf("failed to unpack %s: %w", config.RedactURL(src.ArchiveURL), err)
+	}
+
+	// a symlink in the archive must not redirect repo_path out of targetPath
+	if err := cache.AssertNoSymlinkInSubpath(targetPath, src.RepoPath); err != nil {
+		return "", fmt.Errorf("invalid repo_path %q in %s: %w", src.RepoPath, config.RedactURL(src.ArchiveURL), err)
+	}
+
+	outPath := filepath.Join(targetPath, src.RepoPath)
+	if info, err := os.Lstat(outPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory in %s", src.RepoPath, config.RedactURL(src.ArchiveURL))
+	}
+
+	return outPath, nil
+}
+
+func validateSource(src mod.KloneSource) error {
+	if src.RepoURL != "" || src.RepoRef != "" || src.RepoHash != "" {
+		return fmt.Errorf("archive_url %q cannot be combined with repo_url, repo_ref or repo_hash", config.RedactURL(src.ArchiveURL))
+	}
+
+	u, err := url.Parse(src.ArchiveURL)
+	if err != nil {
+		return fmt.Errorf("invalid archive_url %q: %w", config.RedactURL(src.ArchiveURL), err)
+	}
+	// plain http is allowed, the content is verified with the sha256
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("archive_url %q must use https or http", config.RedactURL(src.ArchiveURL))
+	}
+
+	if src.SHA256 == "" {
+		return fmt.Errorf("archive_url %q has no sha256, it is required to pin the archive", config.RedactURL(src.ArchiveURL))
+	}
+	if b, err := hex.DecodeString(src.SHA256); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("invalid sha256 %q for archive_url %q", src.SHA256, config.RedactURL(src.ArchiveURL))
+	}
+
+	if src.StripComponents < 0 {
+		return fmt.Errorf("strip_components of archive_url %q must not be negative", config.RedactURL(src.ArchiveURL))
+	}
+
+	return nil
+}
+
+// maxDownloadSize is the size limit of an archive, which stops a server
+// from filling the disk. It is a variable so that tests can lower it.
+var maxDownloadSize int64 = 4 << 30 // 4 GiB
+
+// download writes the body of archiveURL to w and fails if it is larger
+// than maxDownloadSize or if its sha256 is not wantSHA256.
+func download(ctx context.Context, archiveURL string, wantSHA256 string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// the error of the client repeats the URL without redacting it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to download %s: %w", config.RedactURL(archiveURL), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", config.RedactURL(archiveURL), resp.Status)
+	}
+
+	if resp.ContentLength > maxDownloadSize {
+		return fmt.Errorf("failed to download %s: its size of %d bytes exceeds the limit of %d bytes", config.RedactURL(archiveURL), resp.ContentLength, maxDownloadSize)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", config.RedactURL(archiveURL), err)
+	}
+	if n > maxDownloadSize {
+		return fmt.Errorf("failed to download %s: it exceeds the limit of %d bytes", config.RedactURL(archiveURL), maxDownloadSize)
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(got, wantSHA256) {
+		return fmt.Errorf("sha256 mismatch for %s: pinned %s, got %s", config.RedactURL(archiveURL), wantSHA256, got)
+	}
+
+	return nil
+}
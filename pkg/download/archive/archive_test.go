@@ -0,0 +1,383 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func zipArchive(t *testing.T, entries []testutil.Entry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.Name}
+		header.SetMode(0o644)
+		content := e.Content
+		switch {
+		case e.Link != "":
+			header.SetMode(os.ModeSymlink | 0o777)
+			content = e.Link
+		case strings.HasSuffix(e.Name, "/"):
+			header.SetMode(os.ModeDir | 0o755)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func serve(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestGet(t *testing.T) {
+	entries := []testutil.Entry{
+		{Name: "release-1.0/"},
+		{Name: "release-1.0/README.md", Content: "readme"},
+		{Name: "release-1.0/charts/app/Chart.yaml", Content: "chart"},
+		{Name: "release-1.0/charts/app/run.sh", Content: "#!/bin/sh", Mode: 0o755},
+		{Name: "release-1.0/charts/app/link", Link: "Chart.yaml"},
+	}
+	archives := map[string][]byte{
+		"/release.tar.gz": testutil.TarGz(t, entries),
+		"/release.zip":    zipArchive(t, entries),
+	}
+	server := serve(t, archives)
+
+	for name, content := range archives {
+		t.Run(name, func(t *testing.T) {
+			targetPath := filepath.Join(t.TempDir(), "target")
+			outPath, err := Get(t.Context(), targetPath, mod.KloneSource{
+				ArchiveURL:      server.URL + name,
+				SHA256:          sha256Hex(content),
+				StripComponents: 1,
+				RepoPath:        "charts/app",
+			})
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+
+			if got := readFile(t, filepath.Join(outPath, "Chart.yaml")); got != "chart" {
+				t.Errorf("Chart.yaml = %q, want %q", got, "chart")
+			}
+			if target, err := os.Readlink(filepath.Join(outPath, "link")); err != nil || target != "Chart.yaml" {
+				t.Errorf("Expected link to point to Chart.yaml, but got %q, %v", target, err)
+			}
+			if got := readFile(t, filepath.Join(targetPath, "README.md")); got != "readme" {
+				t.Errorf("README.md = %q, want %q", got, "readme")
+			}
+			if name == "/release.tar.gz" {
+				if info, err := os.Stat(filepath.Join(outPath, "run.sh")); err != nil || info.Mode().Perm() != 0o755 {
+					t.Errorf("Expected run.sh to be executable, but got %v, %v", info, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGet_Errors(t *testing.T) {
+	archive := testutil.TarGz(t, []testutil.Entry{{Name: "a/file", Content: "content"}})
+
+	// a symlink to a directory outside of the target that has the repo_path
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outside, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	escape := testutil.TarGz(t, []testutil.Entry{{Name: "x", Link: outside}})
+
+	server := serve(t, map[string][]byte{"/a.tar.gz": archive, "/a.rar": archive, "/escape.tar.gz": escape})
+
+	tests := []struct {
+		name     string
+		src      mod.KloneSource
+		errMatch string
+	}{
+		{
+			name:     "sha256 mismatch",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/a.tar.gz", SHA256: sha256Hex([]byte("other")), RepoPath: "."},
+			errMatch: "sha256 mismatch",
+		},
+		{
+			name:     "missing sha256",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/a.tar.gz", RepoPath: "."},
+			errMatch: "has no sha256",
+		},
+		{
+			name:     "unknown format",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/a.rar", SHA256: sha256Hex(archive), RepoPath: "."},
+			errMatch: "must end in",
+		},
+		{
+			name:     "not found",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/missing.tar.gz", SHA256: sha256Hex(archive), RepoPath: "."},
+			errMatch: "404",
+		},
+		{
+			name:     "redacted credentials",
+			src:      mod.KloneSource{ArchiveURL: strings.Replace(server.URL, "http://", "http://token@", 1) + "/missing.tar.gz", SHA256: sha256Hex(archive), RepoPath: "."},
+			errMatch: "http://xxxxx@",
+		},
+		{
+			name:     "missing repo_path",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/a.tar.gz", SHA256: sha256Hex(archive), RepoPath: "b"},
+			errMatch: "is not a directory",
+		},
+		{
+			name:     "symlink in repo_path",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/escape.tar.gz", SHA256: sha256Hex(escape), RepoPath: "x/sub"},
+			errMatch: "refusing to traverse symlink",
+		},
+		{
+			name:     "unsupported scheme",
+			src:      mod.KloneSource{ArchiveURL: "file:///etc/a.tar.gz", SHA256: sha256Hex(archive), RepoPath: "."},
+			errMatch: "must use https or http",
+		},
+		{
+			name:     "combined with repo_url",
+			src:      mod.KloneSource{ArchiveURL: server.URL + "/a.tar.gz", RepoURL: "https://github.com/repo", SHA256: sha256Hex(archive), RepoPath: "."},
+			errMatch: "cannot be combined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+				t.Errorf("Get() error = %v, want substring %q", err, tt.errMatch)
+			}
+		})
+	}
+}
+
+func TestDownload_Limit(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// flushing first hides the length of the body
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	old := maxDownloadSize
+	maxDownloadSize = 99
+	t.Cleanup(func() { maxDownloadSize = old })
+
+	for _, path := range []string{"/sized", "/chunked"} {
+		t.Run(path, func(t *testing.T) {
+			err := download(t.Context(), server.URL+path, sha256Hex(content), &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 99 bytes") {
+				t.Errorf("download() error = %v, want the size limit to be exceeded", err)
+			}
+		})
+	}
+}
+
+func TestUnpack_Limits(t *testing.T) {
+	oldSize, oldEntries := maxUnpackedSize, maxEntries
+	maxUnpackedSize, maxEntries = 10, 3
+	t.Cleanup(func() { maxUnpackedSize, maxEntries = oldSize, oldEntries })
+
+	tests := []struct {
+		name     string
+		entries  []testutil.Entry
+		errMatch string
+	}{
+		{
+			name:    "within the limits",
+			entries: []testutil.Entry{{Name: "a/"}, {Name: "a/b", Content: "12345"}, {Name: "a/c", Content: "67890"}},
+		},
+		{
+			name:     "too large file",
+			entries:  []testutil.Entry{{Name: "a", Content: "12345678901"}},
+			errMatch: "more than 10 bytes",
+		},
+		{
+			name:     "too large in total",
+			entries:  []testutil.Entry{{Name: "a", Content: "123456"}, {Name: "b", Content: "789012"}},
+			errMatch: "more than 10 bytes",
+		},
+		{
+			name:     "too many entries",
+			entries:  []testutil.Entry{{Name: "a/"}, {Name: "a/b/"}, {Name: "a/c/"}, {Name: "a/d/"}},
+			errMatch: "more than 3 entries",
+		},
+	}
+
+	for _, tt := range tests {
+		for format, content := range map[format][]byte{
+			formatTarGz: testutil.TarGz(t, tt.entries),
+			formatZip:   zipArchive(t, tt.entries),
+		} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				file := filepath.Join(t.TempDir(), "archive")
+				if err := os.WriteFile(file, content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				f, err := os.Open(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				err = unpack(format, f, t.TempDir(), 0)
+				if tt.errMatch == "" {
+					if err != nil {
+						t.Errorf("unpack() error = %v", err)
+					}
+				} else if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+					t.Errorf("unpack() error = %v, want substring %q", err, tt.errMatch)
+				}
+			})
+		}
+	}
+}
+
+func TestUnpack_Traversal(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testutil.Entry
+	}{
+		{name: "parent segment", entries: []testutil.Entry{{Name: "../evil", Content: "x"}}},
+		{name: "nested parent segment", entries: []testutil.Entry{{Name: "a/../../evil", Content: "x"}}},
+		{name: "absolute", entries: []testutil.Entry{{Name: "/tmp/evil", Content: "x"}}},
+		{name: "backslash parent", entries: []testutil.Entry{{Name: `a\..\..\evil`, Content: "x"}}},
+		{name: "drive letter", entries: []testutil.Entry{{Name: "C:/evil", Content: "x"}}},
+		{name: "empty segment", entries: []testutil.Entry{{Name: "a//evil", Content: "x"}}},
+		{
+			name: "write through symlink",
+			entries: []testutil.Entry{
+				{Name: "a", Link: ".."},
+				{Name: "a/evil", Content: "x"},
+			},
+		},
+		{
+			name: "link through symlink",
+			entries: []testutil.Entry{
+				{Name: "a", Link: "/tmp"},
+				{Name: "a/evil", Link: "x"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for format, content := range map[format][]byte{
+			formatTarGz: testutil.TarGz(t, tt.entries),
+			formatZip:   zipArchive(t, tt.entries),
+		} {
+			t.Run(tt.name+"/"+string(format), func(t *testing.T) {
+				sandbox := t.TempDir()
+				root := filepath.Join(sandbox, "root")
+				if err := os.Mkdir(root, 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				file := filepath.Join(t.TempDir(), "archive")
+				if err := os.WriteFile(file, content, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				f, err := os.Open(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				if err := unpack(format, f, root, 0); err == nil {
+					t.Errorf("Expected unpack to fail")
+				}
+
+				if _, err := os.Lstat(filepath.Join(sandbox, "evil")); !os.IsNotExist(err) {
+					t.Errorf("Expected nothing to be written outside of the root, but got %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestRelPath(t *testing.T) {
+	u := NewUnpacker("root", 1)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "release/", want: ""},
+		{name: "release", want: ""},
+		{name: "./release/a/b", want: filepath.Join("a", "b")},
+		{name: "release/a/", want: "a"},
+		{name: "./", want: ""},
+	}
+
+	for _, tt := range tests {
+		got, err := u.relPath(tt.name)
+		if err != nil {
+			t.Errorf("relPath(%q) returned error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("relPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/internal/pathutil"
+)
+
+type format string
+
+const (
+	formatTar   format = "tar"
+	formatTarGz format = "tar.gz"
+	formatZip   format = "zip"
+)
+
+// formatOf derives the archive format from the file name in archiveURL.
+func formatOf(archiveURL string) (format, error) {
+	u, err := url.Parse(archiveURL)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.ToLower(u.Path)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return formatTarGz, nil
+	case strings.HasSuffix(name, ".tar"):
+		return formatTar, nil
+	case strings.HasSuffix(name, ".zip"):
+		return formatZip, nil
+	}
+
+	return "", fmt.Errorf("archive_url %q must end in .tar, .tar.gz, .tgz or .zip", config.RedactURL(archiveURL))
+}
+
+// unpack extracts the archive in file to root, which must be an empty
+// directory, removing the first strip components from every path.
+func unpack(f format, file *os.File, root string, strip int) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	u := NewUnpacker(root, strip)
+
+	switch f {
+	case formatTar:
+		if err := u.Tar(file); err != nil {
+			return err
+		}
+	case formatTarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+
+		if err := u.Tar(gz); err != nil {
+			return err
+		}
+	case formatZip:
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		zr, err := zip.NewReader(file, info.Size())
+		if err != nil {
+			return err
+		}
+
+		if err := u.unpackZip(zr); err != nil {
+			return err
+		}
+	}
+
+	return u.Close()
+}
+
+// The limits of everything that an Unpacker extracts, which stop an archive
+// from filling the disk. They are variables so that tests can lower them.
+var (
+	maxUnpackedSize int64 = 8 << 30 // 8 GiB
+	maxEntries            = 1 << 20
+)
+
+// Unpacker extracts archive entries below a root directory. Entry paths
+// are validated before anything is written and symlinks are only created
+// by Close, once all files and directories have been written, so that
+// nothing is ever written through one of them.
+type Unpacker struct {
+	root  string
+	strip int
+	links []link
+
+	// size and entries are the totals of all extracted archives
+	size    int64
+	entries int
+}
+
+// NewUnpacker returns an Unpacker that writes to root, which must be an
+// empty directory, removing the first strip components from every path.
+func NewUnpacker(root string, strip int) *Unpacker {
+	return &Unpacker{root: root, strip: strip}
+}
+
+type link struct {
+	rel    string
+	target string
+}
+
+// Tar extracts the entries of the tar stream r. Entries of different
+// archives must not overlap.
+func (u *Unpacker) Tar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			// e.g. the commit id written by "git archive"
+			continue
+		}
+
+		if err := u.countEntry(); err != nil {
+			return err
+		}
+
+		rel, err := u.relPath(header.Name)
+		if err != nil {
+			return err
+		}
+		if rel == "" {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = u.mkdir(rel)
+		case tar.TypeReg:
+			err = u.writeFile(rel, tr, header.FileInfo().Mode())
+		case tar.TypeSymlink:
+			u.links = append(u.links, link{rel: rel, target: header.Linkname})
+		case tar.TypeLink:
+			err = fmt.Errorf("%q is a hard link, which is not supported", header.Name)
+		default:
+			err = fmt.Errorf("%q has unsupported type %q", header.Name, header.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (u *Unpacker) unpackZip(zr *zip.Reader) error {
+	for _, f := range zr.File {
+		if err := u.countEntry(); err != nil {
+			return err
+		}
+
+		rel, err := u.relPath(f.Name)
+		if err != nil {
+			return err
+		}
+		if rel == "" {
+			continue
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = u.mkdir(rel)
+		case mode&fs.ModeSymlink != 0:
+			var target []byte
+			if target, err = u.readZipFile(f); err == nil {
+				u.links = append(u.links, link{rel: rel, target: string(target)})
+			}
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err == nil {
+				err = u.writeFile(rel, rc, mode)
+				rc.Close()
+			}
+		default:
+			err = fmt.Errorf("%q has unsupported type %s", f.Name, mode.Type())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *Unpacker) readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var buf bytes.Buffer
+	if err := u.copy(&buf, rc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (u *Unpacker) countEntry() error {
+	u.entries++
+	if u.entries > maxEntries {
+		return fmt.Errorf("archive has more than %d entries", maxEntries)
+	}
+	return nil
+}
+
+// copy copies r to w and fails once the extracted archives exceed
+// maxUnpackedSize, without reading much further than the limit.
+func (u *Unpacker) copy(w io.Writer, r io.Reader) error {
+	n, err := io.Copy(w, io.LimitReader(r, maxUnpackedSize-u.size+1))
+	u.size += n
+	if err != nil {
+		return err
+	}
+	if u.size > maxUnpackedSize {
+		return fmt.Errorf("archive unpacks to more than %d bytes", maxUnpackedSize)
+	}
+	return nil
+}
+
+// relPath returns the path of an archive entry relative to the root, after
+// removing the stripped components, or "" if the entry is stripped
+// entirely. Like folder_name, entry names must be relative and must not
+// contain empty or traversal segments; a leading "./" and a trailing "/"
+// are allowed.
+func (u *Unpacker) relPath(name string) (string, error) {
+	// Windows separators and drive letters are rejected on every GOOS, so
+	// that an archive unpacks the same everywhere
+	normalised := strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(normalised, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" || pathutil.HasWindowsDrivePrefix(normalised) {
+		return "", fmt.Errorf("invalid path %q in archive: absolute paths and volume prefixes are not allowed", name)
+	}
+
+	normalised = strings.TrimPrefix(normalised, "./")
+	normalised = strings.TrimSuffix(normalised, "/")
+	if normalised == "" || normalised == "." {
+		return "", nil
+	}
+
+	segments := strings.Split(normalised, "/")
+	for _, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", fmt.Errorf("invalid path %q in archive: empty or traversal segment %q", name, seg)
+		}
+	}
+
+	if len(segments) <= u.strip {
+		return "", nil
+	}
+
+	return filepath.Join(segments[u.strip:]...), nil
+}
+
+func (u *Unpacker) mkdir(rel string) error {
+	return os.MkdirAll(filepath.Join(u.root, rel), 0o755)
+}
+
+func (u *Unpacker) writeFile(rel string, r io.Reader, mode fs.FileMode) error {
+	path := filepath.Join(u.root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// like git, only the executable bit is kept
+	perm := fs.FileMode(0o644)
+	if mode&0o111 != 0 {
+		perm = 0o755
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if err := u.copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// File writes the contents of r to the entry name, as if it was a regular
+// file in an archive.
+func (u *Unpacker) File(name string, r io.Reader, mode fs.FileMode) error {
+	rel, err := u.relPath(name)
+	if err != nil {
+		return err
+	}
+	if rel == "" {
+		return fmt.Errorf("invalid file name %q in archive", name)
+	}
+
+	return u.writeFile(rel, r, mode)
+}
+
+// Close creates the symlinks of all extracted archives.
+func (u *Unpacker) Close() error {
+	for _, l := range u.links {
+		// an earlier link must never redirect where a later one is created
+		if err := cache.AssertNoSymlinkInSubpath(u.root, filepath.Dir(l.rel)); err != nil {
+			return err
+		}
+
+		path := filepath.Join(u.root, l.rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.Symlink(l.target, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
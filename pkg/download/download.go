@@ -0,0 +1,64 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package download
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cert-manager/klone/pkg/download/archive"
+	"github.com/cert-manager/klone/pkg/download/git"
+	"github.com/cert-manager/klone/pkg/download/local"
+	"github.com/cert-manager/klone/pkg/download/oci"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// Get downloads src to targetPath with the downloader for its kind and
+// returns the path of src.RepoPath in it.
+func Get(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
+	switch kind := src.Kind(); kind {
+	case mod.SourceGit:
+		return git.Get(ctx, targetPath, src)
+	case mod.SourceArchive:
+		return archive.Get(ctx, targetPath, src)
+	case mod.SourceOCI:
+		return oci.Get(ctx, targetPath, src)
+	case mod.SourceLocal:
+		return local.Get(ctx, targetPath, src)
+	default:
+		return "", fmt.Errorf("unsupported source kind %q", kind)
+	}
+}
+
+// ResolveRef returns the version that src.RepoRef currently refers to: a
+// commit hash for git sources, a manifest digest for OCI sources and the
+// content hash for local sources. Archives are pinned by their sha256 and
+// cannot be resolved.
+func ResolveRef(ctx context.Context, src mod.KloneSource) (git.ResolvedRef, error) {
+	switch kind := src.Kind(); kind {
+	case mod.SourceGit:
+		return git.ResolveRef(ctx, src.RepoURL, src.RepoRef)
+	case mod.SourceOCI:
+		digest, err := oci.ResolveRef(ctx, src.RepoURL, src.RepoRef)
+		return git.ResolvedRef{Hash: digest}, err
+	case mod.SourceLocal:
+		hash, err := local.ResolveRef(ctx, src)
+		return git.ResolvedRef{Hash: hash}, err
+	default:
+		return git.ResolvedRef{}, fmt.Errorf("%s sources cannot be resolved", kind)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// RemoteRef is a ref advertised by a remote repository. As in the output of
+// "git ls-remote", the commit an annotated tag points to is listed as a
+// separate ref whose name ends in "^{}".
+type RemoteRef struct {
+	Name string
+	Hash string
+}
+
+// Backend talks to remote git repositories.
+type Backend interface {
+	// ListRefs returns the refs of repoURL. If patterns are given, only the
+	// refs matching one of them are returned, using the same rules as
+	// "git ls-remote".
+	ListRefs(ctx context.Context, repoURL string, patterns ...string) ([]RemoteRef, error)
+	// Checkout writes the files below subPath of commit hash of repoURL to
+	// root/subPath. root must not exist yet or be empty. Only hash is
+	// fetched, without any history.
+	Checkout(ctx context.Context, root string, repoURL string, hash string, subPath string) error
+	// Log returns the commits that are reachable from the commit to but not
+	// from the commit from and that change files below subPath, newest
+	// first.
+	Log(ctx context.Context, repoURL string, from string, to string, subPath string) ([]Commit, error)
+}
+
+// Commit is a commit in the history of a remote repository.
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+var hashRegexp = regexp.MustCompile(`^[0-9a-f]{40}(?:[0-9a-f]{24})?$`)
+
+const (
+	// BackendExec runs the git binary.
+	BackendExec = config.GitBackendExec
+	// BackendGoGit talks to remotes in-process and does not need a git
+	// binary.
+	BackendGoGit = config.GitBackendGoGit
+)
+
+// backendFromConfig returns the git_backend of the klone config. By default
+// the git binary is used if it is available.
+func backendFromConfig(ctx context.Context) (Backend, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.GitBackend {
+	case BackendExec:
+		return execBackend{}, nil
+	case BackendGoGit:
+		return goGitBackend{}, nil
+	default:
+		if _, err := exec.LookPath("git"); err != nil {
+			return goGitBackend{}, nil
+		}
+		return execBackend{}, nil
+	}
+}
+
+// matchRefPattern reports whether the ref name matches pattern the way
+// "git ls-remote" matches its patterns: the pattern must equal the name or
+// one or more of its trailing path components.
+func matchRefPattern(name string, pattern string) bool {
+	return name == pattern || strings.HasSuffix(name, "/"+pattern)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestMatchRefPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{name: "refs/heads/main", pattern: "main", want: true},
+		{name: "refs/heads/main", pattern: "heads/main", want: true},
+		{name: "refs/heads/main", pattern: "refs/heads/main", want: true},
+		{name: "refs/heads/main", pattern: "ain", want: false},
+		{name: "refs/tags/v1.0.0^{}", pattern: "v1.0.0", want: false},
+		{name: "HEAD", pattern: "HEAD", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := matchRefPattern(tt.name, tt.pattern); got != tt.want {
+			t.Errorf("matchRefPattern(%q, %q) = %v, want %v", tt.name, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestBackends(t *testing.T) {
+	repo, git := testutil.NewGitRepo(t)
+	testutil.WriteFiles(t, repo, map[string]string{
+		"README.md":          "root\n",
+		"modules/go/go.mk":   "go\n",
+		"modules/go/sub/a":   "a\n",
+		"modules/go/run.sh":  "#!/bin/sh\n",
+		"modules/other/file": "other\n",
+	})
+	if err := os.Chmod(filepath.Join(repo, "modules/go/run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("go.mk", filepath.Join(repo, "modules/go/link")); err != nil {
+		t.Skipf("skip: symlinks unsupported in this environment: %v", err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	git("tag", "-a", "-m", "v1.0.0", "v1.0.0")
+	hash := git("rev-parse", "HEAD")
+	tagHash := git("rev-parse", "v1.0.0")
+	git("commit", "-q", "--allow-empty", "-m", "second")
+
+	var listed [][]RemoteRef
+	for _, name := range []string{BackendExec, BackendGoGit} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("KLONE_GIT_BACKEND", name)
+
+			backend, err := backendFromConfig(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			refs, err := backend.ListRefs(t.Context(), repo)
+			if err != nil {
+				t.Fatalf("ListRefs: %v", err)
+			}
+			slices.SortFunc(refs, func(a, b RemoteRef) int { return strings.Compare(a.Name, b.Name) })
+			listed = append(listed, refs)
+
+			for _, pin := range []string{hash, tagHash} {
+				targetPath := filepath.Join(t.TempDir(), "checkout")
+				outPath, err := Get(t.Context(), targetPath, mod.KloneSource{RepoURL: repo, RepoHash: pin, RepoPath: "modules/go"})
+				if err != nil {
+					t.Fatalf("Get(%s): %v", pin, err)
+				}
+				if err := os.RemoveAll(filepath.Join(outPath, ".git")); err != nil {
+					t.Fatal(err)
+				}
+
+				for file, want := range map[string]string{"go.mk": "go\n", "sub/a": "a\n", "run.sh": "#!/bin/sh\n"} {
+					got, err := os.ReadFile(filepath.Join(outPath, file))
+					if err != nil || string(got) != want {
+						t.Errorf("Expected %s to contain %q, but got %q, %v", file, want, got, err)
+					}
+				}
+
+				if info, err := os.Stat(filepath.Join(outPath, "run.sh")); err != nil || info.Mode().Perm()&0o100 == 0 {
+					t.Errorf("Expected run.sh to be executable, but got %v, %v", info, err)
+				}
+
+				if target, err := os.Readlink(filepath.Join(outPath, "link")); err != nil || target != "go.mk" {
+					t.Errorf("Expected link to point to go.mk, but got %q, %v", target, err)
+				}
+
+				if _, err := os.Stat(filepath.Join(targetPath, "modules/other")); !os.IsNotExist(err) {
+					t.Errorf("Expected modules/other not to be checked out, but got %v", err)
+				}
+			}
+		})
+	}
+
+	if len(listed) == 2 && !slices.Equal(listed[0], listed[1]) {
+		t.Errorf("Expected both backends to list the same refs, but got\n%v\n%v", listed[0], listed[1])
+	}
+}
+
+func TestBackendFromConfig_Unknown(t *testing.T) {
+	t.Setenv("KLONE_GIT_BACKEND", "svn")
+
+	if _, err := backendFromConfig(t.Context()); err == nil {
+		t.Errorf("Expected an error for an unknown backend")
+	}
+}
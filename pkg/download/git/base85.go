@@ -0,0 +1,73 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// base85Alphabet is the alphabet of the base85 encoding of git, which
+// differs from the ASCII85 of encoding/ascii85.
+const base85Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+// encodeBase85 encodes data in groups of 4 bytes, padding the last group
+// with zeros, as git does in binary patches.
+func encodeBase85(data []byte) string {
+	var b strings.Builder
+	for i := 0; i < len(data); i += 4 {
+		var group uint32
+		for j := range 4 {
+			group <<= 8
+			if i+j < len(data) {
+				group |= uint32(data[i+j])
+			}
+		}
+
+		var digits [5]byte
+		for j := 4; j >= 0; j-- {
+			digits[j] = base85Alphabet[group%85]
+			group /= 85
+		}
+		b.Write(digits[:])
+	}
+	return b.String()
+}
+
+// decodeBase85 decodes size bytes from the groups of 5 characters in s.
+func decodeBase85(s string, size int) ([]byte, error) {
+	if len(s)%5 != 0 || len(s)/5*4 < size {
+		return nil, fmt.Errorf("invalid base85 data of %d characters for %d bytes", len(s), size)
+	}
+
+	data := make([]byte, 0, len(s)/5*4)
+	for i := 0; i < len(s); i += 5 {
+		var group uint64
+		for j := range 5 {
+			digit := strings.IndexByte(base85Alphabet, s[i+j])
+			if digit < 0 {
+				return nil, fmt.Errorf("invalid base85 character %q", s[i+j])
+			}
+			group = group*85 + uint64(digit)
+		}
+		if group > 0xffffffff {
+			return nil, fmt.Errorf("invalid base85 group %q", s[i:i+5])
+		}
+		data = append(data, byte(group>>24), byte(group>>16), byte(group>>8), byte(group))
+	}
+	return data[:size], nil
+}
@@ -17,28 +17,41 @@ limitations under the License.
 package git
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v5"
 
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/logging"
 	"github.com/cert-manager/klone/pkg/mod"
 )
 
 func Get(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
-	if err := validateRepoURL(src.RepoURL); err != nil {
+	remote, err := remoteURL(ctx, src.RepoURL)
+	if err != nil {
+		return "", err
+	}
+
+	backend, err := backendFromConfig(ctx)
+	if err != nil {
 		return "", err
 	}
 
-	fmt.Fprintf(os.Stdout, "Cloning %s from %s to %s on commit %s\n", src.RepoPath, src.RepoURL, targetPath, src.RepoHash)
+	attrs := []any{"repo_url", config.RedactURL(src.RepoURL), "repo_path", src.RepoPath, "commit", src.RepoHash, "dir", targetPath}
+	if remote != src.RepoURL {
+		attrs = append(attrs, "mirror", config.RedactURL(remote))
+	}
+	logging.FromContext(ctx).Info("cloning", attrs...)
 
-	if err := sparseCheckout(ctx, targetPath, src.RepoURL, src.RepoHash, []string{src.RepoPath}); err != nil {
+	if err := backend.Checkout(ctx, targetPath, remote, src.RepoHash, src.RepoPath); err != nil {
 		return "", err
 	}
 
@@ -47,57 +60,101 @@ func Get(ctx context.Context, targetPath string, src mod.KloneSource) (string, e
 
 const gitRetryDelay = 5 * time.Second
 
-func runGitCmd(ctx context.Context, root string, stdout io.Writer, stderr io.Writer, args ...string) error {
-	hardened := append([]string{
-		"-c", "protocol.ext.allow=never",
-	}, args...)
-
-	do := func() (struct{}, error) {
-		// dummy return value to match the interface of backoff.Operation
-		ret := struct{}{}
+// runGitCmd runs git with args in root and retries it if it fails, unless
+// it fails with an AuthError. git authenticates to remote with the
+// credentials of the klone config. The standard output of git is written to
+// stdout, or captured like its standard error if stdout is nil.
+func runGitCmd(ctx context.Context, root string, remote string, stdout io.Writer, args ...string) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
 
-		cmd := exec.CommandContext(ctx, "git", hardened...)
+	env, err := credentialEnv(cfg, remote)
+	if err != nil {
+		return err
+	}
 
-		cmd.Dir = root
-		cmd.Env = append(os.Environ(), cmd.Env...)
+	return retry(ctx, func() error {
+		cmd := gitCommand(ctx, root, args...)
+		cmd.Env = append(cmd.Env, env...)
 		// Disable Git terminal prompts in case we're running with a tty
 		cmd.Env = append(cmd.Env, "GIT_TERMINAL_PROMPT=false")
 
-		cmd.Stdout = stdout
-		cmd.Stderr = stderr
+		return stopOnAuthError(classifyAuthError(cfg, remote, runCommand(ctx, cmd, stdout)))
+	})
+}
 
-		if err := cmd.Start(); err != nil {
-			return ret, err
-		}
+// runLocalGitCmd runs a git command that does not access the network, so
+// unlike runGitCmd it is never retried. Repository discovery is limited to
+// root itself, so that a git repository enclosing root is never used.
+func runLocalGitCmd(ctx context.Context, root string, stdout io.Writer, args ...string) error {
+	cmd := gitCommand(ctx, root, args...)
+	cmd.Env = append(cmd.Env, "GIT_CEILING_DIRECTORIES="+filepath.Dir(root))
 
-		if err := cmd.Wait(); err != nil {
-			return ret, fmt.Errorf("git command failed: %v", err)
-		}
+	return runCommand(ctx, cmd, stdout)
+}
 
-		return ret, nil
-	}
+// gitCommand returns a hardened git command that runs args in root.
+func gitCommand(ctx context.Context, root string, args ...string) *exec.Cmd {
+	hardened := append([]string{
+		"-c", "protocol.ext.allow=never",
+	}, args...)
 
-	_, err := backoff.Retry(ctx, do, backoff.WithMaxTries(getRetryCount()), backoff.WithBackOff(backoff.NewConstantBackOff(gitRetryDelay)))
-	return err
+	cmd := exec.CommandContext(ctx, "git", hardened...)
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	return cmd
 }
 
-func getRetryCount() uint {
-	// TODO: add centralized config management defining env vars + maybe a global config file for klone
-	retryCountRaw := os.Getenv("KLONE_GIT_RETRY_ATTEMPTS")
-	if retryCountRaw == "" {
-		return 1
+// runCommand runs cmd, writing its standard output to stdout. The output
+// that is not written to stdout is logged at debug level, and attached to
+// the returned error if cmd fails.
+func runCommand(ctx context.Context, cmd *exec.Cmd, stdout io.Writer) error {
+	args := make([]string, 0, len(cmd.Args)-1)
+	for _, arg := range cmd.Args[1:] {
+		args = append(args, config.RedactURL(arg))
 	}
 
-	retryCount, err := strconv.Atoi(retryCountRaw)
+	logger := logging.FromContext(ctx)
+	logger.Debug("running command", "args", args, "dir", cmd.Dir)
+
+	output := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	if stdout == nil {
+		cmd.Stdout = output
+	}
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	if output.Len() > 0 {
+		logger.Debug("command output", "args", args, "output", output.String())
+	}
 	if err != nil {
-		return 1
+		if msg := strings.TrimSpace(output.String()); msg != "" {
+			return fmt.Errorf("git command failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("git command failed: %w", err)
 	}
 
-	if retryCount <= 0 {
-		return 1
+	return nil
+}
+
+// retry calls fn until it succeeds, up to the retry_attempts of the klone
+// config.
+func retry(ctx context.Context, fn func() error) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
 	}
 
-	return uint(retryCount)
+	do := func() (struct{}, error) {
+		// dummy return value to match the interface of backoff.Operation
+		return struct{}{}, fn()
+	}
+
+	_, err = backoff.Retry(ctx, do, backoff.WithMaxTries(uint(cfg.RetryAttempts)), backoff.WithBackOff(backoff.NewConstantBackOff(gitRetryDelay)))
+	return err
 }
 
 func sparseCheckout(ctx context.Context, root string, repoURL string, branch string, patterns []string) error {
@@ -109,24 +166,24 @@ func sparseCheckout(ctx context.Context, root string, repoURL string, branch str
 		return err
 	}
 
-	if err := runGitCmd(ctx, root, os.Stdout, os.Stderr, "clone", "--depth=1", "--filter=blob:none", "--no-checkout", "--", repoURL, "."); err != nil {
+	if err := runGitCmd(ctx, root, repoURL, nil, "clone", "--depth=1", "--filter=blob:none", "--no-checkout", "--", repoURL, "."); err != nil {
 		return err
 	}
 
-	if err := runGitCmd(ctx, root, os.Stdout, os.Stderr, "config", "advice.detachedHead", "false"); err != nil {
+	if err := runGitCmd(ctx, root, repoURL, nil, "config", "advice.detachedHead", "false"); err != nil {
 		return err
 	}
 
-	if err := runGitCmd(ctx, root, os.Stdout, os.Stderr, "sparse-checkout", "init", "--cone", "--sparse-index"); err != nil {
+	if err := runGitCmd(ctx, root, repoURL, nil, "sparse-checkout", "init", "--cone", "--sparse-index"); err != nil {
 		return err
 	}
 
 	args := append([]string{"sparse-checkout", "set"}, patterns...)
-	if err := runGitCmd(ctx, root, os.Stdout, os.Stderr, args...); err != nil {
+	if err := runGitCmd(ctx, root, repoURL, nil, args...); err != nil {
 		return err
 	}
 
-	if err := runGitCmd(ctx, root, os.Stdout, os.Stderr, "checkout", branch); err != nil {
+	if err := runGitCmd(ctx, root, repoURL, nil, "checkout", branch); err != nil {
 		return err
 	}
 
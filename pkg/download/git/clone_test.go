@@ -0,0 +1,54 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/logging"
+)
+
+func TestRunLocalGitCmd_Output(t *testing.T) {
+	logs := &bytes.Buffer{}
+	ctx := logging.IntoContext(t.Context(), logging.New(logs, slog.LevelDebug))
+
+	// root is not a git repository
+	err := runLocalGitCmd(ctx, t.TempDir(), nil, "rev-parse", "HEAD")
+	if err == nil {
+		t.Fatalf("Expected git rev-parse to fail outside of a repository")
+	}
+	if !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("Expected the output of git to be attached to the error, but got %v", err)
+	}
+
+	for _, want := range []string{`msg="running command"`, "rev-parse", `msg="command output"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected the debug logs to contain %q, but got:\n%s", want, logs)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err := runLocalGitCmd(ctx, t.TempDir(), out, "--version"); err != nil {
+		t.Fatalf("git --version: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "git version") {
+		t.Errorf("Expected the standard output to be written to out, but got %q", out)
+	}
+}
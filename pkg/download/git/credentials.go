@@ -0,0 +1,227 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// AuthError is returned if a git remote requires credentials that are not
+// configured, or rejects the configured ones.
+type AuthError struct {
+	// Host is the host of the remote.
+	Host string
+	// Configured is set if credentials are configured for Host.
+	Configured bool
+	Err        error
+}
+
+func (e *AuthError) Error() string {
+	if e.Configured {
+		return fmt.Sprintf("authentication to %s failed, check its credentials in the klone config: %v", e.Host, e.Err)
+	}
+	return fmt.Sprintf("authentication to %s failed, no credentials are configured for it in the klone config (see \"klone config\"): %v", e.Host, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// authFailures are the messages git prints if a remote requires or rejects
+// credentials.
+var authFailures = []string{
+	"Authentication failed",
+	"could not read Username",
+	"could not read Password",
+	"terminal prompts disabled",
+	"Permission denied (publickey",
+	"The requested URL returned error: 401",
+	"The requested URL returned error: 403",
+}
+
+// classifyAuthError returns an AuthError for remote if err is caused by
+// missing or rejected credentials, and err otherwise.
+func classifyAuthError(cfg *config.Config, remote string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	isAuth := errors.Is(err, transport.ErrAuthenticationRequired) || errors.Is(err, transport.ErrAuthorizationFailed)
+	for _, failure := range authFailures {
+		isAuth = isAuth || strings.Contains(err.Error(), failure)
+	}
+	if !isAuth {
+		return err
+	}
+
+	_, host, _ := parseRemote(remote)
+	_, configured := cfg.CredentialFor(host)
+	return &AuthError{Host: host, Configured: configured, Err: err}
+}
+
+// stopOnAuthError stops retrying once err is an AuthError, retrying does
+// not fix credentials.
+func stopOnAuthError(err error) error {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return backoff.Permanent(err)
+	}
+	return err
+}
+
+// parseRemote returns the scheme, host name and user of a git remote URL.
+// scp-like URLs (user@host:path) have the "ssh" scheme; local paths have
+// neither scheme nor host.
+func parseRemote(remote string) (scheme string, host string, user string) {
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", "", ""
+		}
+		return u.Scheme, u.Hostname(), u.User.Username()
+	}
+
+	// like git, a slash before the colon makes it a local path
+	if colon := strings.Index(remote, ":"); colon > 0 && !strings.Contains(remote[:colon], "/") {
+		if at := strings.Index(remote[:colon], "@"); at > 0 {
+			return "ssh", remote[at+1 : colon], remote[:at]
+		}
+	}
+
+	return "", "", ""
+}
+
+// credentialEnv returns the env vars that make the git binary authenticate
+// to remote with the credential configured for its host. Secrets are only
+// passed in the environment, never as arguments: a token is sent as an
+// "http.<url>.extraHeader" set through GIT_CONFIG_COUNT, an SSH key is
+// selected with GIT_SSH_COMMAND.
+func credentialEnv(cfg *config.Config, remote string) ([]string, error) {
+	scheme, host, _ := parseRemote(remote)
+	credential, ok := cfg.CredentialFor(host)
+	if !ok {
+		return nil, nil
+	}
+
+	switch scheme {
+	case "http", "https":
+		token, err := credential.Token()
+		if err != nil || token == "" {
+			return nil, err
+		}
+
+		u, err := url.Parse(remote)
+		if err != nil {
+			return nil, err
+		}
+
+		// keep the config entries that are already set in the environment
+		count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+		count = max(count, 0)
+		return []string{
+			"GIT_CONFIG_COUNT=" + strconv.Itoa(count+1),
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", count, u.Scheme, u.Host),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", count, basicAuth(credential, token)),
+		}, nil
+	case "ssh":
+		if credential.SSHKey == "" {
+			return nil, nil
+		}
+		return []string{
+			"GIT_SSH_COMMAND=ssh -i " + shellQuote(credential.SSHKey) + " -o IdentitiesOnly=yes",
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// retryGoGit calls fn like retry, with the go-git auth method for the
+// credential configured for the host of remote.
+func retryGoGit(ctx context.Context, remote string, fn func(auth transport.AuthMethod) error) error {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	auth, err := goGitAuth(cfg, remote)
+	if err != nil {
+		return err
+	}
+
+	return retry(ctx, func() error {
+		return stopOnAuthError(classifyAuthError(cfg, remote, fn(auth)))
+	})
+}
+
+// goGitAuth returns the go-git auth method for the credential configured
+// for the host of remote, or nil if there is none.
+func goGitAuth(cfg *config.Config, remote string) (transport.AuthMethod, error) {
+	scheme, host, user := parseRemote(remote)
+	credential, ok := cfg.CredentialFor(host)
+	if !ok {
+		return nil, nil
+	}
+
+	switch scheme {
+	case "http", "https":
+		token, err := credential.Token()
+		if err != nil || token == "" {
+			return nil, err
+		}
+		return &githttp.BasicAuth{Username: username(credential), Password: token}, nil
+	case "ssh":
+		if credential.SSHKey == "" {
+			return nil, nil
+		}
+		if user == "" {
+			user = "git"
+		}
+		return gitssh.NewPublicKeysFromFile(user, credential.SSHKey, "")
+	}
+
+	return nil, nil
+}
+
+func username(credential config.Credential) string {
+	if credential.Username != "" {
+		return credential.Username
+	}
+	return config.DefaultUsername
+}
+
+func basicAuth(credential config.Credential, token string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username(credential) + ":" + token))
+}
+
+// shellQuote quotes s for sh, which runs GIT_SSH_COMMAND.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// The modes of files in a patch, as git stores them.
+const (
+	modeFile       = 0o100644
+	modeExecutable = 0o100755
+	modeSymlink    = 0o120000
+)
+
+// diffContext is the number of unchanged lines around every change.
+const diffContext = 3
+
+// patchFile is a file or symlink in a directory that is compared by Diff.
+type patchFile struct {
+	mode uint32
+	path string
+}
+
+// data returns the content of a file, or the target of a symlink.
+func (f *patchFile) data() ([]byte, error) {
+	if f.mode == modeSymlink {
+		target, err := os.Readlink(f.path)
+		return []byte(filepath.ToSlash(target)), err
+	}
+	return os.ReadFile(f.path)
+}
+
+// Diff writes a patch in the format of "git diff --binary" to w that turns
+// the directory root/oldName into root/newName, with the paths in the
+// patch relative to both directories. It reports whether there were any
+// differences. Like git, it only compares files and symlinks, and ignores
+// the permissions of files except for the executable bit.
+func Diff(ctx context.Context, root string, oldName string, newName string, w io.Writer) (bool, error) {
+	oldFiles, err := listPatchFiles(filepath.Join(root, oldName))
+	if err != nil {
+		return false, err
+	}
+	newFiles, err := listPatchFiles(filepath.Join(root, newName))
+	if err != nil {
+		return false, err
+	}
+
+	paths := slices.Sorted(maps.Keys(oldFiles))
+	for path := range newFiles {
+		if _, ok := oldFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+
+	out := bufio.NewWriter(w)
+	changed := false
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		oldFile, newFile := oldFiles[path], newFiles[path]
+		oldPath, newPath := oldName+"/"+path, newName+"/"+path
+
+		// a file that was replaced by a symlink, or the other way around, is
+		// deleted and created again
+		if oldFile != nil && newFile != nil && (oldFile.mode == modeSymlink) != (newFile.mode == modeSymlink) {
+			if err := writeFileDiff(out, oldPath, newPath, oldFile, nil); err != nil {
+				return false, err
+			}
+			oldFile = nil
+			changed = true
+		}
+
+		fileChanged, err := writeFileDiffIfChanged(out, oldPath, newPath, oldFile, newFile)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || fileChanged
+	}
+
+	return changed, out.Flush()
+}
+
+// listPatchFiles returns the files and symlinks below dir by their slash
+// separated path relative to dir.
+func listPatchFiles(dir string) (map[string]*patchFile, error) {
+	files := map[string]*patchFile{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var mode uint32
+		switch {
+		case entry.IsDir():
+			return nil
+		case entry.Type()&fs.ModeSymlink != 0:
+			mode = modeSymlink
+		case entry.Type().IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			mode = modeFile
+			if info.Mode().Perm()&0o100 != 0 {
+				mode = modeExecutable
+			}
+		default:
+			return fmt.Errorf("cannot diff %s: unsupported file type %s", path, entry.Type())
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = &patchFile{mode: mode, path: path}
+		return nil
+	})
+	return files, err
+}
+
+// writeFileDiffIfChanged writes the patch of a single file, unless its
+// content and mode did not change.
+func writeFileDiffIfChanged(w *bufio.Writer, oldPath string, newPath string, oldFile *patchFile, newFile *patchFile) (bool, error) {
+	if oldFile != nil && newFile != nil && oldFile.mode == newFile.mode {
+		oldData, err := oldFile.data()
+		if err != nil {
+			return false, err
+		}
+		newData, err := newFile.data()
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(oldData, newData) {
+			return false, nil
+		}
+	}
+
+	return true, writeFileDiff(w, oldPath, newPath, oldFile, newFile)
+}
+
+// writeFileDiff writes the patch that turns oldFile into newFile. Either of
+// them is nil if the file is created or deleted.
+func writeFileDiff(w *bufio.Writer, oldPath string, newPath string, oldFile *patchFile, newFile *patchFile) error {
+	var oldData, newData []byte
+	var err error
+	if oldFile != nil {
+		if oldData, err = oldFile.data(); err != nil {
+			return err
+		}
+	}
+	if newFile != nil {
+		if newData, err = newFile.data(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "diff --git %s %s\n", quotePatchPath(oldPath), quotePatchPath(newPath))
+	switch {
+	case oldFile == nil:
+		fmt.Fprintf(w, "new file mode %06o\n", newFile.mode)
+	case newFile == nil:
+		fmt.Fprintf(w, "deleted file mode %06o\n", oldFile.mode)
+	case oldFile.mode != newFile.mode:
+		fmt.Fprintf(w, "old mode %06o\nnew mode %06o\n", oldFile.mode, newFile.mode)
+		if bytes.Equal(oldData, newData) {
+			return nil
+		}
+	}
+
+	oldHash, newHash := plumbing.ZeroHash, plumbing.ZeroHash
+	if oldFile != nil {
+		oldHash = plumbing.ComputeHash(plumbing.BlobObject, oldData)
+	}
+	if newFile != nil {
+		newHash = plumbing.ComputeHash(plumbing.BlobObject, newData)
+	}
+	// git only applies binary patches with the full hashes
+	fmt.Fprintf(w, "index %s..%s", oldHash, newHash)
+	if oldFile != nil && newFile != nil && oldFile.mode == newFile.mode {
+		fmt.Fprintf(w, " %06o", oldFile.mode)
+	}
+	fmt.Fprintln(w)
+
+	if isBinary(oldData) || isBinary(newData) {
+		fmt.Fprintln(w, "GIT binary patch")
+		if err := writeBinaryLiteral(w, newData); err != nil {
+			return err
+		}
+		return writeBinaryLiteral(w, oldData)
+	}
+
+	if len(oldData) == 0 && len(newData) == 0 {
+		// an empty file is created or deleted, there are no hunks
+		return nil
+	}
+
+	oldLabel, newLabel := oldPath, newPath
+	if oldFile == nil {
+		oldLabel = "/dev/null"
+	}
+	if newFile == nil {
+		newLabel = "/dev/null"
+	}
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", patchLabel(oldLabel), patchLabel(newLabel))
+
+	writeHunks(w, string(oldData), string(newData))
+	return nil
+}
+
+// patchLabel quotes the path on a "---" or "+++" line. Like git, it appends
+// a tab to paths with spaces, so that they are not mistaken for a path
+// followed by a timestamp.
+func patchLabel(path string) string {
+	quoted := quotePatchPath(path)
+	if quoted == path && strings.Contains(path, " ") {
+		return path + "\t"
+	}
+	return quoted
+}
+
+// isBinary reports whether data is binary, using the same heuristic as git:
+// the first 8000 bytes contain a NUL byte.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// diffLine is a line of a hunk, with its newline if it has one.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// writeHunks writes the hunks of a unified diff between two texts.
+func writeHunks(w *bufio.Writer, oldText string, newText string) {
+	var lines []diffLine
+	for _, d := range diff.Do(oldText, newText) {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, line := range splitLines(d.Text) {
+			lines = append(lines, diffLine{op: op, text: line})
+		}
+	}
+
+	// oldLine and newLine are the number of lines before lines[i]
+	oldLine, newLine := 0, 0
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// a hunk starts with up to diffContext unchanged lines and ends with
+		// as many; changes with up to twice as many unchanged lines between
+		// them share a hunk
+		start := max(0, i-diffContext)
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(lines))
+
+		hunkOld, hunkNew := 0, 0
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				hunkOld++
+			}
+			if line.op != '-' {
+				hunkNew++
+			}
+		}
+		oldStart := oldLine - (i - start)
+		newStart := newLine - (i - start)
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", hunkRange(oldStart, hunkOld), hunkRange(newStart, hunkNew))
+		for _, line := range lines[start:end] {
+			w.WriteByte(line.op)
+			w.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				w.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		oldLine += hunkOld - (i - start)
+		newLine += hunkNew - (i - start)
+		i = end
+	}
+}
+
+// hunkRange formats the range of a hunk header, with start being the
+// number of lines before the hunk.
+func hunkRange(start int, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+// splitLines splits text after every newline. The last line has no newline
+// if text does not end with one.
+func splitLines(text string) []string {
+	var lines []string
+	for text != "" {
+		i := strings.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		lines = append(lines, text[:i])
+		text = text[i:]
+	}
+	return lines
+}
+
+// writeBinaryLiteral writes a "literal" hunk of a binary patch: data
+// compressed with zlib, encoded with base85 in lines of up to 52 bytes.
+func writeBinaryLiteral(w *bufio.Writer, data []byte) error {
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "literal %d\n", len(data))
+	for chunk := range slices.Chunk(compressed.Bytes(), 52) {
+		if len(chunk) <= 26 {
+			w.WriteByte(byte('A' + len(chunk) - 1))
+		} else {
+			w.WriteByte(byte('a' + len(chunk) - 27))
+		}
+		w.WriteString(encodeBase85(chunk))
+		w.WriteByte('\n')
+	}
+	w.WriteByte('\n')
+	return nil
+}
+
+// quotePatchPath quotes a path like git does if it contains a double quote,
+// a backslash, a control character or a non-ASCII byte.
+func quotePatchPath(path string) string {
+	needsQuotes := false
+	for i := 0; i < len(path); i++ {
+		if c := path[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			needsQuotes = true
+			break
+		}
+	}
+	if !needsQuotes {
+		return path
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\v':
+			b.WriteString(`\v`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, `\%03o`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
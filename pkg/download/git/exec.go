@@ -0,0 +1,127 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// execBackend implements Backend by running the git binary.
+type execBackend struct{}
+
+func (execBackend) ListRefs(ctx context.Context, repoURL string, patterns ...string) ([]RemoteRef, error) {
+	outBuffer := &bytes.Buffer{}
+	args := append([]string{"ls-remote", "--", repoURL}, patterns...)
+	if err := runGitCmd(ctx, ".", repoURL, outBuffer, args...); err != nil {
+		return nil, err
+	}
+
+	return parseLsRemote(outBuffer.Bytes())
+}
+
+func (execBackend) Checkout(ctx context.Context, root string, repoURL string, hash string, subPath string) error {
+	return sparseCheckout(ctx, root, repoURL, hash, []string{subPath})
+}
+
+func (execBackend) Log(ctx context.Context, repoURL string, from string, to string, subPath string) ([]Commit, error) {
+	root, err := os.MkdirTemp("", "klone-log-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+
+	if err := runLocalGitCmd(ctx, root, nil, "init", "-q", "--bare"); err != nil {
+		return nil, err
+	}
+
+	// only trees and commits are needed to filter by path
+	if err := runGitCmd(ctx, root, repoURL, nil, "fetch", "-q", "--filter=blob:none", "--no-tags", "--", repoURL, to); err != nil {
+		return nil, err
+	}
+	if err := runLocalGitCmd(ctx, root, nil, "cat-file", "-e", from); err != nil {
+		// from is not an ancestor of to
+		if err := runGitCmd(ctx, root, repoURL, nil, "fetch", "-q", "--filter=blob:none", "--no-tags", "--", repoURL, from); err != nil {
+			return nil, err
+		}
+	}
+
+	out := &bytes.Buffer{}
+	if err := runLocalGitCmd(ctx, root, out, "log", "--format=%H%x00%an%x00%aI%x00%s", from+".."+to, "--", subPath); err != nil {
+		return nil, err
+	}
+
+	return parseLog(out.Bytes())
+}
+
+// parseLog parses the output of "git log" with the format
+// "%H%x00%an%x00%aI%x00%s".
+func parseLog(out []byte) ([]Commit, error) {
+	var commits []Commit
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+
+		fields := strings.SplitN(scanner.Text(), "\x00", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected log output line %q", scanner.Text())
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected date in log output line %q: %w", scanner.Text(), err)
+		}
+
+		commits = append(commits, Commit{Hash: fields[0], Author: fields[1], Date: date, Subject: fields[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// parseLsRemote parses the output of "git ls-remote".
+func parseLsRemote(out []byte) ([]RemoteRef, error) {
+	var refs []RemoteRef
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected ls-remote output line %q", scanner.Text())
+		}
+
+		refs = append(refs, RemoteRef{Name: fields[1], Hash: fields[0]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
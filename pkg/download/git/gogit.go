@@ -0,0 +1,522 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// goGitBackend implements Backend in-process with go-git. Objects are
+// fetched into a temporary directory that is removed afterwards; the files
+// of the requested commit are written directly, without a .git folder.
+type goGitBackend struct{}
+
+var installFileTransport = sync.OnceFunc(func() {
+	// by default, go-git runs git-upload-pack for local repositories; serve
+	// them in-process instead so that no git binary is needed
+	client.InstallProtocol("file", server.NewServer(localLoader{}))
+})
+
+// localLoader loads local bare and non-bare repositories. Unlike
+// server.DefaultLoader, it reads non-bare repositories from their .git
+// folder.
+type localLoader struct{}
+
+func (localLoader) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	fs := osfs.New(ep.Path)
+	if _, err := fs.Stat("config"); err != nil {
+		if fs, err = fs.Chroot(".git"); err != nil {
+			return nil, err
+		}
+		if _, err := fs.Stat("config"); err != nil {
+			return nil, transport.ErrRepositoryNotFound
+		}
+	}
+
+	return filesystem.NewStorage(fs, cache.NewObjectLRUDefault()), nil
+}
+
+func newGoGitRemote(store storage.Storer, repoURL string) *gogit.Remote {
+	installFileTransport()
+
+	return gogit.NewRemote(store, &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+}
+
+func (goGitBackend) ListRefs(ctx context.Context, repoURL string, patterns ...string) ([]RemoteRef, error) {
+	list, err := listGoGitRefs(ctx, repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs of %s: %w", config.RedactURL(repoURL), err)
+	}
+
+	hashes := make(map[plumbing.ReferenceName]plumbing.Hash, len(list))
+	for _, ref := range list {
+		if ref.Type() == plumbing.HashReference {
+			hashes[ref.Name()] = ref.Hash()
+		}
+	}
+
+	refs := make([]RemoteRef, 0, len(list))
+	for _, ref := range list {
+		name := ref.Name().String()
+		if len(patterns) > 0 && !matchesAnyRefPattern(name, patterns) {
+			continue
+		}
+
+		hash := ref.Hash()
+		if ref.Type() == plumbing.SymbolicReference {
+			// ls-remote lists symbolic refs (e.g. HEAD) with the hash of
+			// their target
+			target, ok := hashes[ref.Target()]
+			if !ok {
+				continue
+			}
+			hash = target
+		}
+
+		refs = append(refs, RemoteRef{Name: name, Hash: hash.String()})
+	}
+
+	return refs, nil
+}
+
+// listGoGitRefs lists the refs of repoURL, including the peeled commits of
+// annotated tags as "<tag>^{}".
+func listGoGitRefs(ctx context.Context, repoURL string) ([]*plumbing.Reference, error) {
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if ep.Protocol == "file" {
+		// the in-process server does not advertise peeled tags
+		return listLocalRefs(ep)
+	}
+
+	remote := newGoGitRemote(memory.NewStorage(), repoURL)
+
+	var list []*plumbing.Reference
+	err = retryGoGit(ctx, repoURL, func(auth transport.AuthMethod) error {
+		var err error
+		list, err = remote.ListContext(ctx, &gogit.ListOptions{PeelingOption: gogit.AppendPeeled, Auth: auth})
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			list, err = nil, nil
+		}
+		return err
+	})
+	return list, err
+}
+
+func listLocalRefs(ep *transport.Endpoint) ([]*plumbing.Reference, error) {
+	storage, err := localLoader{}.Load(ep)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := storage.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*plumbing.Reference
+	if err := iter.ForEach(func(ref *plumbing.Reference) error {
+		list = append(list, ref)
+		if ref.Type() != plumbing.HashReference || !ref.Name().IsTag() {
+			return nil
+		}
+
+		tag, err := object.GetTag(storage, ref.Hash())
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			// lightweight tag
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		commit, err := tag.Commit()
+		if err != nil {
+			return err
+		}
+		list = append(list, plumbing.NewHashReference(ref.Name()+"^{}", commit.Hash))
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// newTempStorage returns a storage in a new temporary directory, so that
+// fetched objects are not all kept in memory, and a function that removes
+// it.
+func newTempStorage() (*filesystem.Storage, func(), error) {
+	dir, err := os.MkdirTemp("", "klone-gogit-*")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storage := filesystem.NewStorage(osfs.New(dir), cache.NewObjectLRUDefault())
+	return storage, func() { os.RemoveAll(dir) }, nil
+}
+
+func matchesAnyRefPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchRefPattern(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (goGitBackend) Checkout(ctx context.Context, root string, repoURL string, hash string, subPath string) error {
+	if !plumbing.IsHash(hash) {
+		return fmt.Errorf("the go-git backend can only fetch full commit hashes, got %q", hash)
+	}
+
+	storage, cleanup, err := newTempStorage()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	remote := newGoGitRemote(storage, repoURL)
+	if err := retryGoGit(ctx, repoURL, func(auth transport.AuthMethod) error {
+		return fetchCommit(ctx, remote, hash, auth)
+	}); err != nil {
+		return fmt.Errorf("failed to fetch %s from %s: %w", hash, config.RedactURL(repoURL), err)
+	}
+
+	commit, err := peelCommit(storage, hash)
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	subPath = path.Clean(filepath.ToSlash(subPath))
+	if subPath != "." {
+		if tree, err = tree.Tree(subPath); err != nil {
+			return fmt.Errorf("%s does not exist at %s: %w", subPath, hash, err)
+		}
+	}
+
+	dest := filepath.Join(root, filepath.FromSlash(subPath))
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+
+	// symlinks are created last, so that no file is ever written through
+	// one of them
+	var links []*object.File
+	if err := writeTree(dest, "", tree, &links); err != nil {
+		return err
+	}
+	for _, link := range links {
+		target, err := link.Contents()
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, filepath.Join(dest, filepath.FromSlash(link.Name))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (goGitBackend) Log(ctx context.Context, repoURL string, from string, to string, subPath string) ([]Commit, error) {
+	storage, cleanup, err := newTempStorage()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	remote := newGoGitRemote(storage, repoURL)
+	if err := retryGoGit(ctx, repoURL, func(auth transport.AuthMethod) error {
+		return fetchHistory(ctx, remote, to, auth)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s from %s: %w", to, config.RedactURL(repoURL), err)
+	}
+	if _, err := storage.EncodedObject(plumbing.AnyObject, plumbing.NewHash(from)); err != nil {
+		// from is not an ancestor of to
+		if err := retryGoGit(ctx, repoURL, func(auth transport.AuthMethod) error {
+			return fetchHistory(ctx, remote, from, auth)
+		}); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s from %s: %w", from, config.RedactURL(repoURL), err)
+		}
+	}
+
+	fromCommit, err := peelCommit(storage, from)
+	if err != nil {
+		return nil, err
+	}
+	toCommit, err := peelCommit(storage, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// like "git log from..to", exclude everything reachable from from
+	excluded := map[plumbing.Hash]bool{}
+	if err := object.NewCommitPreorderIter(fromCommit, nil, nil).ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	subPath = path.Clean(filepath.ToSlash(subPath))
+	iter := object.NewCommitPathIterFromIter(func(name string) bool {
+		return subPath == "." || name == subPath || strings.HasPrefix(name, subPath+"/")
+	}, object.NewCommitPreorderIter(toCommit, excluded, nil), true)
+
+	var commits []Commit
+	if err := iter.ForEach(func(c *object.Commit) error {
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		commits = append(commits, Commit{
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			Date:    c.Author.When,
+			Subject: subject,
+		})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// peelCommit returns the commit hash refers to, peeling annotated tags.
+func peelCommit(storer storer.EncodedObjectStorer, hash string) (*object.Commit, error) {
+	obj, err := object.GetObject(storer, plumbing.NewHash(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	switch obj := obj.(type) {
+	case *object.Commit:
+		return obj, nil
+	case *object.Tag:
+		// annotated tags are pinned by the hash of the tag object
+		return obj.Commit()
+	default:
+		return nil, fmt.Errorf("%s is a %s, not a commit", hash, obj.Type())
+	}
+}
+
+// fetchHistory fetches the commit hash with its history. go-git cannot
+// filter out file contents, so unlike "git fetch --filter=blob:none" they
+// are fetched too.
+func fetchHistory(ctx context.Context, remote *gogit.Remote, hash string, auth transport.AuthMethod) error {
+	err := remote.FetchContext(ctx, &gogit.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{gitconfig.RefSpec(hash + ":refs/klone/" + hash)},
+		Tags:     gogit.NoTags,
+		Auth:     auth,
+	})
+	if errors.Is(err, gogit.ErrExactSHA1NotSupported) {
+		err = remote.FetchContext(ctx, &gogit.FetchOptions{
+			RefSpecs: allBranchesAndTags,
+			Tags:     gogit.NoTags,
+			Auth:     auth,
+		})
+	}
+	if errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+// allBranchesAndTags is fetched from servers that do not allow fetching
+// arbitrary commits, skipping other refs such as the pull requests of
+// GitHub.
+var allBranchesAndTags = []gitconfig.RefSpec{
+	"+refs/heads/*:refs/klone/heads/*",
+	"+refs/tags/*:refs/klone/tags/*",
+}
+
+// fetchCommit fetches the single commit hash, without history. Servers that
+// do not allow fetching arbitrary commits only serve the commits of their
+// refs: hash is fetched through a ref that points to it, or, if it is not
+// the tip of any ref, with the full history of all branches and tags.
+func fetchCommit(ctx context.Context, remote *gogit.Remote, hash string, auth transport.AuthMethod) error {
+	opts := &gogit.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{gitconfig.RefSpec(hash + ":refs/klone/fetch")},
+		Depth:    1,
+		Tags:     gogit.NoTags,
+		Auth:     auth,
+	}
+	err := remote.FetchContext(ctx, opts)
+	if errors.Is(err, gogit.ErrExactSHA1NotSupported) {
+		var name plumbing.ReferenceName
+		if name, err = refPointingTo(ctx, remote, hash, auth); err != nil {
+			return err
+		}
+
+		opts.RefSpecs, opts.Depth = allBranchesAndTags, 0
+		if name != "" {
+			opts.RefSpecs = []gitconfig.RefSpec{gitconfig.RefSpec("+" + name + ":refs/klone/fetch")}
+			// the in-process server of local repositories cannot serve
+			// shallow fetches
+			if !isLocalRemote(remote) {
+				opts.Depth = 1
+			}
+		}
+		err = remote.FetchContext(ctx, opts)
+	}
+	if errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+func isLocalRemote(remote *gogit.Remote) bool {
+	ep, err := transport.NewEndpoint(remote.Config().URLs[0])
+	return err == nil && ep.Protocol == "file"
+}
+
+// refPointingTo returns the name of a branch or tag of the remote that
+// points to hash, or "" if there is none. An annotated tag is returned for
+// the hash of its commit too.
+func refPointingTo(ctx context.Context, remote *gogit.Remote, hash string, auth transport.AuthMethod) (plumbing.ReferenceName, error) {
+	list, err := remote.ListContext(ctx, &gogit.ListOptions{PeelingOption: gogit.AppendPeeled, Auth: auth})
+	if err != nil {
+		return "", err
+	}
+
+	for _, ref := range list {
+		name := plumbing.ReferenceName(strings.TrimSuffix(ref.Name().String(), "^{}"))
+		if ref.Type() == plumbing.HashReference && ref.Hash().String() == hash && (name.IsBranch() || name.IsTag()) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// writeTree writes the entries of tree to dest/prefix and appends the
+// symlinks it encounters to links.
+func writeTree(dest string, prefix string, tree *object.Tree, links *[]*object.File) error {
+	for _, entry := range tree.Entries {
+		name := path.Join(prefix, entry.Name)
+		if !isSafeTreeEntryName(entry.Name) {
+			return fmt.Errorf("refusing to check out unsafe path %q", name)
+		}
+		destPath := filepath.Join(dest, filepath.FromSlash(name))
+
+		switch entry.Mode {
+		case filemode.Dir:
+			subTree, err := tree.Tree(entry.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.Mkdir(destPath, 0o755); err != nil {
+				return err
+			}
+			if err := writeTree(dest, name, subTree, links); err != nil {
+				return err
+			}
+		case filemode.Submodule:
+			// like git, leave an empty directory for submodules
+			if err := os.Mkdir(destPath, 0o755); err != nil {
+				return err
+			}
+		case filemode.Symlink:
+			file, err := tree.TreeEntryFile(&entry)
+			if err != nil {
+				return err
+			}
+			file.Name = name
+			*links = append(*links, file)
+		case filemode.Regular, filemode.Deprecated, filemode.Executable:
+			file, err := tree.TreeEntryFile(&entry)
+			if err != nil {
+				return err
+			}
+			if err := writeFile(destPath, file, entry.Mode == filemode.Executable); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported mode %s of %q", entry.Mode, name)
+		}
+	}
+
+	return nil
+}
+
+func writeFile(destPath string, file *object.File, executable bool) error {
+	perm := os.FileMode(0o644)
+	if executable {
+		perm = 0o755
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// isSafeTreeEntryName rejects entry names that git itself refuses to check
+// out, which a malicious remote could use to write outside of the
+// destination or into a .git folder.
+func isSafeTreeEntryName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		!strings.ContainsAny(name, `/\`) &&
+		!strings.EqualFold(name, ".git")
+}
@@ -17,28 +17,60 @@ limitations under the License.
 package git
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"os"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/config"
 )
 
 func GetHash(ctx context.Context, repoURL string, ref string) (string, error) {
-	if err := validateRepoURL(repoURL); err != nil {
+	remote, err := remoteURL(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	backend, err := backendFromConfig(ctx)
+	if err != nil {
 		return "", err
 	}
 
-	outBuffer := &bytes.Buffer{}
-	if err := runGitCmd(ctx, ".", outBuffer, os.Stderr, "ls-remote", "--", repoURL, ref); err != nil {
+	remoteRefs, err := backend.ListRefs(ctx, remote, ref)
+	if err != nil {
 		return "", err
 	}
 
-	outFields := bytes.Fields(outBuffer.Bytes())
-	if len(outFields) != 2 {
-		return "", fmt.Errorf("could not find %s@%s", repoURL, ref)
+	refs := peelRefs(remoteRefs)
+	if len(refs) != 1 {
+		return "", fmt.Errorf("could not find %s@%s", config.RedactURL(repoURL), ref)
+	}
+
+	var hash string
+	for _, refHash := range refs {
+		hash = refHash
 	}
 
-	hash := outFields[0]
+	return hash, nil
+}
+
+// peelRefs maps the ref names in refs to commit hashes. For annotated tags,
+// the hash of the peeled commit ("<tag>^{}") is returned instead of the hash
+// of the tag object, if it is listed.
+func peelRefs(refs []RemoteRef) map[string]string {
+	hashes := map[string]string{}
+	peeled := map[string]string{}
+
+	for _, ref := range refs {
+		if base, ok := strings.CutSuffix(ref.Name, "^{}"); ok {
+			peeled[base] = ref.Hash
+			continue
+		}
+		hashes[ref.Name] = ref.Hash
+	}
+
+	for name, hash := range peeled {
+		hashes[name] = hash
+	}
 
-	return string(hash), nil
+	return hashes
 }
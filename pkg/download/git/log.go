@@ -0,0 +1,47 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// Log returns the commits of repoURL between the pinned hashes from and to
+// that change files below subPath, newest first, like
+// "git log from..to -- subPath".
+func Log(ctx context.Context, repoURL string, from string, to string, subPath string) ([]Commit, error) {
+	remote, err := remoteURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hash := range []string{from, to} {
+		if !hashRegexp.MatchString(hash) {
+			return nil, fmt.Errorf("invalid commit hash %q for %s", hash, config.RedactURL(repoURL))
+		}
+	}
+
+	backend, err := backendFromConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.Log(ctx, remote, from, to, subPath)
+}
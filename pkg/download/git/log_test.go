@@ -0,0 +1,89 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestLog(t *testing.T) {
+	repo, git := testutil.NewGitRepo(t)
+
+	commit := func(files map[string]string, subject string) string {
+		testutil.WriteFiles(t, repo, files)
+		git("add", ".")
+		git("commit", "-q", "-m", subject+"\n\nbody")
+		return git("rev-parse", "HEAD")
+	}
+
+	from := commit(map[string]string{"modules/go/go.mk": "1\n"}, "initial")
+	first := commit(map[string]string{"modules/go/go.mk": "2\n"}, "Change go")
+	commit(map[string]string{"modules/other/file": "other\n"}, "Change other")
+	last := commit(map[string]string{"modules/go/sub/a": "a\n"}, "Add sub")
+
+	for _, name := range []string{BackendExec, BackendGoGit} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("KLONE_GIT_BACKEND", name)
+
+			commits, err := Log(t.Context(), repo, from, last, "modules/go")
+			if err != nil {
+				t.Fatalf("Log: %v", err)
+			}
+
+			var subjects []string
+			for _, c := range commits {
+				subjects = append(subjects, c.Subject)
+				if c.Author != "klone" || time.Since(c.Date) > time.Hour {
+					t.Errorf("unexpected author or date in %+v", c)
+				}
+			}
+			if want := []string{"Add sub", "Change go"}; !slices.Equal(subjects, want) {
+				t.Errorf("Log() subjects = %q, want %q", subjects, want)
+			}
+			if len(commits) == 2 && (commits[0].Hash != last || commits[1].Hash != first) {
+				t.Errorf("Log() hashes = %s, %s, want %s, %s", commits[0].Hash, commits[1].Hash, last, first)
+			}
+
+			all, err := Log(t.Context(), repo, from, last, ".")
+			if err != nil || len(all) != 3 {
+				t.Errorf("Expected 3 commits for the whole repository, but got %+v, %v", all, err)
+			}
+		})
+	}
+
+	if _, err := Log(t.Context(), repo, "--upload-pack=evil", last, "."); err == nil {
+		t.Errorf("Expected Log to reject an invalid hash")
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	commits, err := parseLog([]byte("abc\x00Jane Doe\x002026-01-02T03:04:05+01:00\x00Fix: a\x00b\n\n"))
+	if err != nil {
+		t.Fatalf("parseLog: %v", err)
+	}
+	if len(commits) != 1 || commits[0].Author != "Jane Doe" || commits[0].Subject != "Fix: a\x00b" || commits[0].Date.Year() != 2026 {
+		t.Errorf("parseLog() = %+v", commits)
+	}
+
+	if _, err := parseLog([]byte("abc\x00Jane Doe\n")); err == nil {
+		t.Errorf("Expected parseLog to reject a truncated line")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/logging"
+)
+
+type mirrorsKey struct{}
+
+// WithMirrors returns a copy of ctx that carries the mirrors of a project.
+// They are used together with the mirrors of the klone config.
+func WithMirrors(ctx context.Context, mirrors []config.Mirror) context.Context {
+	return context.WithValue(ctx, mirrorsKey{}, mirrors)
+}
+
+// remoteURL returns the URL that repoURL is fetched from, after rewriting
+// it with the mirrors of ctx and the klone config, and validates it.
+func remoteURL(ctx context.Context, repoURL string) (string, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	project, _ := ctx.Value(mirrorsKey{}).([]config.Mirror)
+
+	remote := rewriteURL(repoURL, project, cfg.Mirrors)
+	if remote != repoURL {
+		logging.FromContext(ctx).Debug("using mirror", "repo_url", config.RedactURL(repoURL), "mirror", config.RedactURL(remote))
+	}
+
+	if err := validateRepoURL(remote); err != nil {
+		return "", err
+	}
+
+	return remote, nil
+}
+
+// rewriteURL replaces the longest instead_of prefix of repoURL in any of
+// the mirror lists with the url of its mirror, like git does for its
+// insteadOf settings. If two mirrors have the same prefix, the one in the
+// earlier list is used.
+func rewriteURL(repoURL string, lists ...[]config.Mirror) string {
+	var best *config.Mirror
+	for _, mirrors := range lists {
+		for i, mirror := range mirrors {
+			if !strings.HasPrefix(repoURL, mirror.InsteadOf) {
+				continue
+			}
+			if best == nil || len(mirror.InsteadOf) > len(best.InsteadOf) {
+				best = &mirrors[i]
+			}
+		}
+	}
+
+	if best == nil {
+		return repoURL
+	}
+	return best.URL + strings.TrimPrefix(repoURL, best.InsteadOf)
+}
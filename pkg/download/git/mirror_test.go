@@ -0,0 +1,62 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+func TestRewriteURL(t *testing.T) {
+	project := []config.Mirror{
+		{URL: "https://project.example.com/", InsteadOf: "https://github.com/"},
+	}
+	global := []config.Mirror{
+		{URL: "https://global.example.com/", InsteadOf: "https://github.com/"},
+		{URL: "https://global.example.com/cm/", InsteadOf: "https://github.com/cert-manager/"},
+		{URL: "ssh://git@internal/", InsteadOf: "https://gitlab.com/"},
+	}
+
+	tests := []struct {
+		repoURL string
+		want    string
+	}{
+		// the longest prefix wins
+		{"https://github.com/cert-manager/klone.git", "https://global.example.com/cm/klone.git"},
+		// the project wins ties
+		{"https://github.com/jetstack/foo.git", "https://project.example.com/jetstack/foo.git"},
+		{"https://gitlab.com/foo.git", "ssh://git@internal/foo.git"},
+		{"https://bitbucket.org/foo.git", "https://bitbucket.org/foo.git"},
+	}
+
+	for _, test := range tests {
+		if got := rewriteURL(test.repoURL, project, global); got != test.want {
+			t.Errorf("rewriteURL(%q) = %q, want %q", test.repoURL, got, test.want)
+		}
+	}
+}
+
+func TestRemoteURL_Validated(t *testing.T) {
+	t.Setenv(config.ConfigEnv, "")
+	t.Setenv("KLONE_MIRRORS", "")
+
+	ctx := WithMirrors(t.Context(), []config.Mirror{{URL: "ext::sh -c touch% /tmp/pwned ", InsteadOf: "https://github.com/"}})
+	if _, err := remoteURL(ctx, "https://github.com/cert-manager/klone.git"); err == nil {
+		t.Errorf("Expected the rewritten URL to be validated")
+	}
+}
@@ -0,0 +1,849 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+)
+
+// ApplyPatch applies the patch file patchPath to the directory root. Paths
+// in the patch are expected to be relative to root with one leading
+// component (e.g. "a/" and "b/"), as written by Diff.
+//
+// Like "git apply", it accepts the output of "git diff", including binary,
+// mode and rename patches, as well as plain unified diffs. Hunks must match
+// exactly but may have moved, and either the whole patch is applied or
+// nothing is changed. No git binary is needed.
+func ApplyPatch(ctx context.Context, root string, patchPath string) error {
+	data, err := os.ReadFile(patchPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := parsePatch(data)
+	if err != nil {
+		return fmt.Errorf("invalid patch %s: %w", patchPath, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("invalid patch %s: no file changes found", patchPath)
+	}
+
+	tree := &patchTree{root: filepath.Clean(root), changes: map[string]*treeFile{}}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := tree.apply(file); err != nil {
+			return err
+		}
+	}
+
+	return tree.write()
+}
+
+// filePatch is the patch of a single file. The paths are relative to the
+// root the patch is applied to, oldPath is empty for new files and newPath
+// for deleted files.
+type filePatch struct {
+	oldPath string
+	newPath string
+	// the modes are zero if they are not part of the patch
+	oldMode uint32
+	newMode uint32
+	// oldHash is the full hash of the old content, if the patch has one
+	oldHash string
+
+	isNew    bool
+	isDelete bool
+	isRename bool
+
+	hunks  []hunk
+	binary *binaryHunk
+}
+
+type hunk struct {
+	oldStart int
+	oldLines int
+	newStart int
+	newLines int
+	lines    []diffLine
+}
+
+// binaryHunk is the decompressed forward hunk of a binary patch.
+type binaryHunk struct {
+	delta bool
+	data  []byte
+}
+
+type patchParser struct {
+	lines []string
+	i     int
+}
+
+// parsePatch parses the file patches of a git or plain unified diff. Lines
+// outside of file patches, such as a commit message, are ignored.
+func parsePatch(data []byte) ([]*filePatch, error) {
+	p := &patchParser{lines: splitLines(string(data))}
+
+	var files []*filePatch
+	for p.i < len(p.lines) {
+		var file *filePatch
+		var err error
+		switch {
+		case strings.HasPrefix(p.lines[p.i], "diff --git "):
+			file, err = p.parseGitFile()
+		case p.atFileHeader():
+			file, err = p.parseUnifiedFile()
+		default:
+			p.i++
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.i, err)
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// line returns the current line without its newline.
+func (p *patchParser) line() string {
+	if p.i >= len(p.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(p.lines[p.i], "\n")
+}
+
+// atFileHeader reports whether the current line starts the "---" and "+++"
+// header of a plain unified diff.
+func (p *patchParser) atFileHeader() bool {
+	return p.i+2 < len(p.lines) &&
+		strings.HasPrefix(p.lines[p.i], "--- ") &&
+		strings.HasPrefix(p.lines[p.i+1], "+++ ") &&
+		strings.HasPrefix(p.lines[p.i+2], "@@ ")
+}
+
+func (p *patchParser) parseGitFile() (*filePatch, error) {
+	file := &filePatch{}
+	// the names in the header are only used if no other line has them,
+	// which is the case for mode changes, binary patches and empty files
+	headerOld, headerNew := parseGitHeaderNames(strings.TrimPrefix(p.line(), "diff --git "))
+	p.i++
+
+	var err error
+headers:
+	for ; p.i < len(p.lines); p.i++ {
+		line := p.line()
+		switch {
+		case strings.HasPrefix(line, "old mode "):
+			file.oldMode, err = parseMode(strings.TrimPrefix(line, "old mode "))
+		case strings.HasPrefix(line, "new mode "):
+			file.newMode, err = parseMode(strings.TrimPrefix(line, "new mode "))
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.isDelete = true
+			file.oldMode, err = parseMode(strings.TrimPrefix(line, "deleted file mode "))
+		case strings.HasPrefix(line, "new file mode "):
+			file.isNew = true
+			file.newMode, err = parseMode(strings.TrimPrefix(line, "new file mode "))
+		case strings.HasPrefix(line, "rename from "):
+			file.isRename = true
+			file.oldPath, err = unquotePatchPath(strings.TrimPrefix(line, "rename from "))
+		case strings.HasPrefix(line, "rename to "):
+			file.isRename = true
+			file.newPath, err = unquotePatchPath(strings.TrimPrefix(line, "rename to "))
+		case strings.HasPrefix(line, "copy from "):
+			file.oldPath, err = unquotePatchPath(strings.TrimPrefix(line, "copy from "))
+		case strings.HasPrefix(line, "copy to "):
+			file.newPath, err = unquotePatchPath(strings.TrimPrefix(line, "copy to "))
+		case strings.HasPrefix(line, "index "):
+			hashes, _, _ := strings.Cut(strings.TrimPrefix(line, "index "), " ")
+			if oldHash, _, _ := strings.Cut(hashes, ".."); len(oldHash) == 40 {
+				file.oldHash = oldHash
+			}
+		case strings.HasPrefix(line, "similarity index "), strings.HasPrefix(line, "dissimilarity index "):
+		default:
+			break headers
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(p.line(), "--- ") {
+		if err := p.parseFileHeader(file); err != nil {
+			return nil, err
+		}
+	}
+
+	switch line := p.line(); {
+	case line == "GIT binary patch":
+		p.i++
+		if file.binary, err = p.parseBinaryHunk(); err != nil {
+			return nil, err
+		}
+		// the reverse hunk is optional and not needed
+		if line := p.line(); strings.HasPrefix(line, "literal ") || strings.HasPrefix(line, "delta ") {
+			if _, err := p.parseBinaryHunk(); err != nil {
+				return nil, err
+			}
+		}
+	case strings.HasPrefix(line, "Binary files "):
+		return nil, fmt.Errorf("the patch of %s has no binary data, create it with \"git diff --binary\"", headerNew)
+	default:
+		if err := p.parseHunks(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if file.oldPath == "" && !file.isNew {
+		file.oldPath = headerOld
+	}
+	if file.newPath == "" && !file.isDelete {
+		file.newPath = headerNew
+	}
+	if (file.oldPath == "" && !file.isNew) || (file.newPath == "" && !file.isDelete) {
+		return nil, errors.New("patch without a file name")
+	}
+	return file, nil
+}
+
+func (p *patchParser) parseUnifiedFile() (*filePatch, error) {
+	file := &filePatch{}
+	if err := p.parseFileHeader(file); err != nil {
+		return nil, err
+	}
+	file.isNew = file.oldPath == ""
+	file.isDelete = file.newPath == ""
+	if file.isNew && file.isDelete {
+		return nil, errors.New("patch without a file name")
+	}
+
+	return file, p.parseHunks(file)
+}
+
+// parseFileHeader parses the "---" and "+++" lines.
+func (p *patchParser) parseFileHeader(file *filePatch) error {
+	if !strings.HasPrefix(p.line(), "--- ") || !strings.HasPrefix(strings.TrimSuffix(p.lines[min(p.i+1, len(p.lines)-1)], "\n"), "+++ ") {
+		return errors.New(`expected a "---" line followed by a "+++" line`)
+	}
+
+	oldPath, err := parseFileHeaderName(strings.TrimPrefix(p.line(), "--- "))
+	if err != nil {
+		return err
+	}
+	p.i++
+	newPath, err := parseFileHeaderName(strings.TrimPrefix(p.line(), "+++ "))
+	if err != nil {
+		return err
+	}
+	p.i++
+
+	// the names in rename and copy headers take precedence
+	if file.oldPath == "" {
+		file.oldPath = oldPath
+	}
+	if file.newPath == "" {
+		file.newPath = newPath
+	}
+	return nil
+}
+
+// parseFileHeaderName returns the path of a "---" or "+++" line without
+// its first component, or "" for /dev/null.
+func parseFileHeaderName(name string) (string, error) {
+	if !strings.HasPrefix(name, `"`) {
+		// the name may be followed by a tab and a timestamp
+		name, _, _ = strings.Cut(name, "\t")
+		name = strings.TrimRight(name, " ")
+	}
+	name, err := unquotePatchPath(name)
+	if err != nil || name == "/dev/null" {
+		return "", err
+	}
+	return stripPatchComponent(name)
+}
+
+// parseGitHeaderNames returns the paths of a "diff --git" line without their
+// first component, if both are the same. Otherwise, the paths are taken
+// from other lines of the patch.
+func parseGitHeaderNames(names string) (string, string) {
+	if strings.HasPrefix(names, `"`) {
+		end := closingQuote(names)
+		if end < 0 || end+2 > len(names) {
+			return "", ""
+		}
+		oldName, err := unquotePatchPath(names[:end+1])
+		if err != nil {
+			return "", ""
+		}
+		newName, err := unquotePatchPath(names[end+2:])
+		if err != nil {
+			return "", ""
+		}
+		oldPath, oldErr := stripPatchComponent(oldName)
+		newPath, newErr := stripPatchComponent(newName)
+		if oldErr != nil || newErr != nil {
+			return "", ""
+		}
+		return oldPath, newPath
+	}
+
+	// paths with spaces are not quoted, try every space as the separator
+	for i := range len(names) {
+		if names[i] != ' ' {
+			continue
+		}
+		oldPath, oldErr := stripPatchComponent(names[:i])
+		newPath, newErr := stripPatchComponent(names[i+1:])
+		if oldErr == nil && newErr == nil && oldPath == newPath {
+			return oldPath, newPath
+		}
+	}
+	return "", ""
+}
+
+// stripPatchComponent removes the first component of a path in a patch,
+// like "git apply -p1".
+func stripPatchComponent(name string) (string, error) {
+	_, rest, ok := strings.Cut(name, "/")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("invalid path %q in patch: expected a leading directory like a/", name)
+	}
+	return rest, nil
+}
+
+// closingQuote returns the index of the double quote that ends the quoted
+// string at the start of s.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// unquotePatchPath reverses quotePatchPath. git quotes with the escapes of
+// C, which are a subset of those of Go.
+func unquotePatchPath(name string) (string, error) {
+	if !strings.HasPrefix(name, `"`) {
+		return name, nil
+	}
+	path, err := strconv.Unquote(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted path %s in patch", name)
+	}
+	return path, nil
+}
+
+func parseMode(mode string) (uint32, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(mode), 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode %q in patch", mode)
+	}
+	switch value {
+	case modeFile, modeExecutable, modeSymlink:
+		return uint32(value), nil
+	case 0o100664:
+		// written by old versions of git
+		return modeFile, nil
+	default:
+		return 0, fmt.Errorf("unsupported mode %06o in patch", value)
+	}
+}
+
+func (p *patchParser) parseHunks(file *filePatch) error {
+	for strings.HasPrefix(p.line(), "@@ ") {
+		h, err := p.parseHunk()
+		if err != nil {
+			return err
+		}
+		file.hunks = append(file.hunks, h)
+	}
+	return nil
+}
+
+func (p *patchParser) parseHunk() (hunk, error) {
+	var h hunk
+	header := p.line()
+	ranges, _, ok := strings.Cut(strings.TrimPrefix(header, "@@ "), " @@")
+	oldRange, newRange, _ := strings.Cut(ranges, " ")
+	if !ok || !strings.HasPrefix(oldRange, "-") || !strings.HasPrefix(newRange, "+") {
+		return h, fmt.Errorf("invalid hunk header %q", header)
+	}
+	var err error
+	if h.oldStart, h.oldLines, err = parseHunkRange(oldRange[1:]); err != nil {
+		return h, fmt.Errorf("invalid hunk header %q: %w", header, err)
+	}
+	if h.newStart, h.newLines, err = parseHunkRange(newRange[1:]); err != nil {
+		return h, fmt.Errorf("invalid hunk header %q: %w", header, err)
+	}
+	p.i++
+
+	oldLeft, newLeft := h.oldLines, h.newLines
+	for oldLeft > 0 || newLeft > 0 {
+		if p.i >= len(p.lines) {
+			return h, fmt.Errorf("hunk %q is truncated", header)
+		}
+		line := p.line()
+		p.i++
+
+		if strings.HasPrefix(line, `\`) {
+			if err := markNoNewline(&h); err != nil {
+				return h, err
+			}
+			continue
+		}
+
+		// some editors strip the space of empty unchanged lines
+		op, text := byte(' '), "\n"
+		if line != "" {
+			op, text = line[0], line[1:]+"\n"
+		}
+		switch op {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		default:
+			return h, fmt.Errorf("invalid line %q in hunk %q", line, header)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return h, fmt.Errorf("hunk %q has more lines than its header", header)
+		}
+		h.lines = append(h.lines, diffLine{op: op, text: text})
+	}
+
+	if strings.HasPrefix(p.line(), `\`) {
+		p.i++
+		if err := markNoNewline(&h); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// markNoNewline removes the newline of the last line of h, for a "\ No
+// newline at end of file" line.
+func markNoNewline(h *hunk) error {
+	if len(h.lines) == 0 {
+		return errors.New(`unexpected "\" line at the start of a hunk`)
+	}
+	last := &h.lines[len(h.lines)-1]
+	last.text = strings.TrimSuffix(last.text, "\n")
+	return nil
+}
+
+// parseHunkRange parses "start,count" or "start", which has a count of 1.
+func parseHunkRange(r string) (int, int, error) {
+	startText, countText, hasCount := strings.Cut(r, ",")
+	start, err := strconv.Atoi(startText)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", r)
+	}
+	count := 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil || count < 0 {
+			return 0, 0, fmt.Errorf("invalid range %q", r)
+		}
+	}
+	return start, count, nil
+}
+
+// parseBinaryHunk parses a "literal" or "delta" hunk of a binary patch.
+func (p *patchParser) parseBinaryHunk() (*binaryHunk, error) {
+	header := p.line()
+	kind, sizeText, _ := strings.Cut(header, " ")
+	size, err := strconv.Atoi(sizeText)
+	if (kind != "literal" && kind != "delta") || err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid binary hunk header %q", header)
+	}
+	p.i++
+
+	var compressed []byte
+	for ; p.i < len(p.lines) && p.line() != ""; p.i++ {
+		line := p.line()
+		var n int
+		switch c := line[0]; {
+		case 'A' <= c && c <= 'Z':
+			n = int(c-'A') + 1
+		case 'a' <= c && c <= 'z':
+			n = int(c-'a') + 27
+		default:
+			return nil, fmt.Errorf("invalid line %q in binary hunk", line)
+		}
+		data, err := decodeBase85(line[1:], n)
+		if err != nil {
+			return nil, err
+		}
+		compressed = append(compressed, data...)
+	}
+	// skip the empty line that ends the hunk
+	p.i++
+
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("invalid binary hunk: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, int64(size)+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid binary hunk: %w", err)
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("invalid binary hunk: expected %d bytes, got %d", size, len(data))
+	}
+
+	return &binaryHunk{delta: kind == "delta", data: data}, nil
+}
+
+// treeFile is the new state of a file or symlink that a patch changes.
+type treeFile struct {
+	mode uint32
+	// perm is the permissions of the file that the patch changes, or zero
+	// for new files
+	perm fs.FileMode
+	data []byte
+}
+
+// patchTree applies patches to a directory in memory, so that the
+// directory is only written to once the whole patch applies.
+type patchTree struct {
+	root string
+	// changes holds the new state of every changed path, which is nil for
+	// deleted paths
+	changes map[string]*treeFile
+}
+
+// lookup returns the current state of path, or nil if it does not exist.
+func (t *patchTree) lookup(path string) (*treeFile, error) {
+	if file, ok := t.changes[path]; ok {
+		return file, nil
+	}
+
+	fullPath, err := t.fullPath(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(fullPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		return &treeFile{mode: modeSymlink, data: []byte(filepath.ToSlash(target))}, nil
+	case info.Mode().IsRegular():
+		data, err := os.ReadFile(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		mode := uint32(modeFile)
+		if info.Mode().Perm()&0o100 != 0 {
+			mode = modeExecutable
+		}
+		return &treeFile{mode: mode, perm: info.Mode().Perm(), data: data}, nil
+	default:
+		return nil, fmt.Errorf("%s: not a file or symlink", path)
+	}
+}
+
+// fullPath returns the path in the directory for a path in the patch. Like
+// git, it refuses paths outside of the directory or behind a symlink.
+func (t *patchTree) fullPath(path string) (string, error) {
+	local := filepath.FromSlash(path)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("invalid path %q in patch: must be a relative path without ..", path)
+	}
+
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		info, err := os.Lstat(filepath.Join(t.root, filepath.FromSlash(strings.Join(parts[:i], "/"))))
+		if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("%s: affected file is beyond a symbolic link", path)
+		}
+	}
+
+	return filepath.Join(t.root, local), nil
+}
+
+// apply applies the patch of a single file in memory.
+func (t *patchTree) apply(file *filePatch) error {
+	if file.isNew {
+		existing, err := t.lookup(file.newPath)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%s: already exists in working directory", file.newPath)
+		}
+
+		data, err := file.applyTo(file.newPath, nil)
+		if err != nil {
+			return err
+		}
+		mode := file.newMode
+		if mode == 0 {
+			mode = modeFile
+		}
+		t.changes[file.newPath] = &treeFile{mode: mode, data: data}
+		return nil
+	}
+
+	current, err := t.lookup(file.oldPath)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("%s: does not exist in working directory", file.oldPath)
+	}
+	if file.oldMode != 0 && (file.oldMode == modeSymlink) != (current.mode == modeSymlink) {
+		return fmt.Errorf("%s: wrong type", file.oldPath)
+	}
+
+	data, err := file.applyTo(file.oldPath, current.data)
+	if err != nil {
+		return err
+	}
+
+	if file.isDelete {
+		if len(data) != 0 {
+			return fmt.Errorf("%s: removal patch leaves file contents", file.oldPath)
+		}
+		t.changes[file.oldPath] = nil
+		return nil
+	}
+
+	if file.newPath != file.oldPath {
+		existing, err := t.lookup(file.newPath)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%s: already exists in working directory", file.newPath)
+		}
+		if file.isRename {
+			t.changes[file.oldPath] = nil
+		}
+	}
+
+	mode := current.mode
+	if file.newMode != 0 {
+		mode = file.newMode
+	}
+	if (mode == modeSymlink) != (current.mode == modeSymlink) {
+		return fmt.Errorf("%s: the patch changes the type of the file", file.newPath)
+	}
+	t.changes[file.newPath] = &treeFile{mode: mode, perm: current.perm, data: data}
+	return nil
+}
+
+// applyTo returns the content of the file at path after the patch, given
+// its current content.
+func (file *filePatch) applyTo(path string, data []byte) ([]byte, error) {
+	if file.binary == nil {
+		return applyHunks(path, data, file.hunks)
+	}
+
+	if file.oldHash != "" && plumbing.ComputeHash(plumbing.BlobObject, data).String() != file.oldHash {
+		return nil, fmt.Errorf("%s: the binary patch does not apply to the current content", path)
+	}
+	if !file.binary.delta {
+		return file.binary.data, nil
+	}
+	result, err := packfile.PatchDelta(data, file.binary.data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: the binary patch does not apply: %w", path, err)
+	}
+	return result, nil
+}
+
+// applyHunks applies hunks to data. Like git, every hunk must match
+// exactly, but may be found before or after the position in its header.
+// A hunk without trailing context must match the end of the file, and a
+// hunk that starts at the first line must match the start of the file.
+func applyHunks(path string, data []byte, hunks []hunk) ([]byte, error) {
+	lines := splitLines(string(data))
+
+	var out []string
+	pos, offset := 0, 0
+	for _, h := range hunks {
+		var preimage, postimage []string
+		for _, line := range h.lines {
+			if line.op != '+' {
+				preimage = append(preimage, line.text)
+			}
+			if line.op != '-' {
+				postimage = append(postimage, line.text)
+			}
+		}
+
+		trailing := 0
+		for i := len(h.lines) - 1; i >= 0 && h.lines[i].op == ' '; i-- {
+			trailing++
+		}
+
+		// the header counts lines from 1, or has the line after which
+		// lines are inserted
+		expected := h.oldStart - 1
+		if h.oldLines == 0 {
+			expected = h.oldStart
+		}
+
+		at, ok := findLines(lines, preimage, expected+offset, pos, h.oldStart <= 1, trailing == 0)
+		if !ok {
+			return nil, fmt.Errorf("patch failed: %s:%d", path, h.oldStart)
+		}
+
+		out = append(out, lines[pos:at]...)
+		out = append(out, postimage...)
+		pos = at + len(preimage)
+		offset = at - expected
+	}
+	out = append(out, lines[pos:]...)
+
+	return []byte(strings.Join(out, "")), nil
+}
+
+// findLines returns the index of the match of want in lines that is closest
+// to expected, but not before from.
+func findLines(lines []string, want []string, expected int, from int, atStart bool, atEnd bool) (int, bool) {
+	matches := func(at int) bool {
+		switch {
+		case at < from || at+len(want) > len(lines):
+			return false
+		case atStart && at != 0:
+			return false
+		case atEnd && at+len(want) != len(lines):
+			return false
+		}
+		return slices.Equal(lines[at:at+len(want)], want)
+	}
+
+	for distance := 0; expected-distance >= from || expected+distance <= len(lines); distance++ {
+		if matches(expected - distance) {
+			return expected - distance, true
+		}
+		if matches(expected + distance) {
+			return expected + distance, true
+		}
+	}
+	return 0, false
+}
+
+// write writes the changes to the directory, deletions first, so that a
+// deleted file can be replaced by a directory of the same name.
+func (t *patchTree) write() error {
+	paths := slices.Sorted(maps.Keys(t.changes))
+
+	for _, path := range paths {
+		if t.changes[path] != nil {
+			continue
+		}
+		fullPath, err := t.fullPath(path)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		// like git, remove the directories that are empty now
+		for dir := filepath.Dir(fullPath); dir != t.root; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+
+	for _, path := range paths {
+		file := t.changes[path]
+		if file == nil {
+			continue
+		}
+		fullPath, err := t.fullPath(path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+			return err
+		}
+		if err := os.Remove(fullPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		if file.mode == modeSymlink {
+			if err := os.Symlink(filepath.FromSlash(string(file.data)), fullPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.WriteFile(fullPath, file.data, file.permissions()); err != nil {
+			return err
+		}
+		// new files get the permissions of the umask, changed files keep
+		// their permissions except for the executable bits
+		if file.perm != 0 {
+			if err := os.Chmod(fullPath, file.permissions()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// permissions returns the permissions of a regular file after the patch.
+func (file *treeFile) permissions() fs.FileMode {
+	perm := file.perm
+	switch {
+	case perm == 0 && file.mode == modeExecutable:
+		return 0o755
+	case perm == 0:
+		return 0o644
+	case file.mode == modeExecutable:
+		// executable for everyone who can read it
+		return perm | (perm&0o444)>>2
+	default:
+		return perm &^ 0o111
+	}
+}
@@ -0,0 +1,399 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+// diffTrees writes the files of old and new to root/a and root/b and
+// returns the patch between them.
+func diffTrees(t *testing.T, root string, old map[string]string, new map[string]string) []byte {
+	t.Helper()
+
+	testutil.WriteFiles(t, filepath.Join(root, "a"), old)
+	testutil.WriteFiles(t, filepath.Join(root, "b"), new)
+
+	patch := &bytes.Buffer{}
+	changed, err := Diff(t.Context(), root, "a", "b", patch)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !changed {
+		t.Fatalf("Diff reported no changes")
+	}
+	return patch.Bytes()
+}
+
+// applyPatch writes patch to a file and applies it to dir.
+func applyPatch(t *testing.T, dir string, patch []byte) error {
+	t.Helper()
+
+	patchPath := filepath.Join(t.TempDir(), "local.patch")
+	if err := os.WriteFile(patchPath, patch, 0o644); err != nil {
+		t.Fatalf("write patch: %v", err)
+	}
+	return ApplyPatch(t.Context(), dir, patchPath)
+}
+
+// assertSameTree fails if the files of dir and want differ.
+func assertSameTree(t *testing.T, dir string, want string) {
+	t.Helper()
+
+	got, err := listPatchFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles, err := listPatchFiles(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, wantFile := range wantFiles {
+		gotFile, ok := got[path]
+		if !ok {
+			t.Errorf("Expected %s to exist", path)
+			continue
+		}
+		gotData, err := gotFile.data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantData, err := wantFile.data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotFile.mode != wantFile.mode || !bytes.Equal(gotData, wantData) {
+			t.Errorf("%s = %06o %q, want %06o %q", path, gotFile.mode, gotData, wantFile.mode, wantData)
+		}
+	}
+	for path := range got {
+		if _, ok := wantFiles[path]; !ok {
+			t.Errorf("Expected %s not to exist", path)
+		}
+	}
+}
+
+func TestDiffApplyPatch(t *testing.T) {
+	long := strings.Repeat("line\n", 20)
+	upstream := map[string]string{
+		"Makefile":       "all:\n\techo upstream\n",
+		"removed.mk":     "removed\n",
+		"sub/keep.yaml":  "keep: true\n",
+		"long.txt":       "first\n" + long + "middle\n" + long + "last\n",
+		"no-newline.txt": "old",
+		"empty.txt":      "",
+		"with space.txt": "old\n",
+		"binary.bin":     "\x00\x01\x02old",
+		"run.sh":         "#!/bin/sh\n",
+	}
+	modified := map[string]string{
+		"Makefile":       "all:\n\techo patched\n",
+		"added.mk":       "added\n",
+		"sub/keep.yaml":  "keep: true\n",
+		"long.txt":       "FIRST\n" + long + "middle\n" + long + "LAST\n",
+		"no-newline.txt": "new\n",
+		"with space.txt": "new\n",
+		"binary.bin":     "\x00\x01\x02new",
+		"new/empty.txt":  "",
+		"run.sh":         "#!/bin/sh\n",
+	}
+
+	root := t.TempDir()
+	testutil.WriteFiles(t, filepath.Join(root, "a"), upstream)
+	testutil.WriteFiles(t, filepath.Join(root, "b"), modified)
+	if err := os.Chmod(filepath.Join(root, "b", "run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := &bytes.Buffer{}
+	changed, err := Diff(t.Context(), root, "a", "b", patch)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if !changed {
+		t.Fatalf("Diff reported no changes")
+	}
+	for _, want := range []string{
+		"diff --git a/Makefile b/Makefile\n",
+		"@@ -1,4 +1,4 @@\n-first\n+FIRST\n",
+		"\\ No newline at end of file\n",
+		"--- a/with space.txt\t\n",
+		"GIT binary patch\nliteral ",
+		"diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755\n",
+		"diff --git a/new/empty.txt b/new/empty.txt\nnew file mode 100644\nindex 0000000000000000000000000000000000000000..e69de29bb2d1d6434b8b29ae775ad8c2e48c5391\n",
+	} {
+		if !strings.Contains(patch.String(), want) {
+			t.Errorf("Expected the patch to contain %q, but got:\n%s", want, patch)
+		}
+	}
+
+	// Apply the patch to a fresh copy of the upstream content.
+	target := filepath.Join(t.TempDir(), "content")
+	testutil.WriteFiles(t, target, upstream)
+	if err := applyPatch(t, target, patch.Bytes()); err != nil {
+		t.Fatalf("ApplyPatch: %v\n%s", err, patch)
+	}
+	assertSameTree(t, target, filepath.Join(root, "b"))
+
+	// Applying the patch a second time must fail and change nothing.
+	err = applyPatch(t, target, patch.Bytes())
+	if err == nil || !strings.Contains(err.Error(), "Makefile") {
+		t.Errorf("ApplyPatch on already patched content = %v, want error mentioning Makefile", err)
+	}
+	assertSameTree(t, target, filepath.Join(root, "b"))
+}
+
+func TestApplyPatch_Moved(t *testing.T) {
+	long := strings.Repeat("line\n", 20)
+	patch := diffTrees(t, t.TempDir(),
+		map[string]string{"file.txt": "first\n" + long + "middle\n" + long},
+		map[string]string{"file.txt": "first\n" + long + "changed\n" + long},
+	)
+
+	// lines that were added upstream move the hunk
+	target := t.TempDir()
+	testutil.WriteFiles(t, target, map[string]string{"file.txt": "first\nadded\nupstream\n" + long + "middle\n" + long})
+	if err := applyPatch(t, target, patch); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(target, "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first\nadded\nupstream\n" + long + "changed\n" + long; string(data) != want {
+		t.Errorf("file.txt = %q, want %q", data, want)
+	}
+}
+
+func TestApplyPatch_Symlinks(t *testing.T) {
+	testutil.SkipIfNoSymlinks(t)
+
+	root := t.TempDir()
+	testutil.WriteFiles(t, filepath.Join(root, "a"), map[string]string{"target.txt": "target\n", "becomes-link": "file\n"})
+	testutil.WriteFiles(t, filepath.Join(root, "b"), map[string]string{"target.txt": "target\n", "other.txt": "other\n"})
+	for _, link := range []struct{ dir, name, target string }{
+		{"a", "link", "target.txt"},
+		{"b", "link", "other.txt"},
+		{"b", "becomes-link", "target.txt"},
+	} {
+		if err := os.Symlink(link.target, filepath.Join(root, link.dir, link.name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patch := &bytes.Buffer{}
+	if _, err := Diff(t.Context(), root, "a", "b", patch); err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if err := os.Rename(filepath.Join(root, "a"), filepath.Join(root, "c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyPatch(t, filepath.Join(root, "c"), patch.Bytes()); err != nil {
+		t.Fatalf("ApplyPatch: %v\n%s", err, patch)
+	}
+	assertSameTree(t, filepath.Join(root, "c"), filepath.Join(root, "b"))
+
+	// a patch never writes through a symlink
+	outside := t.TempDir()
+	target := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(target, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	patchText := "diff --git a/sub/file.txt b/sub/file.txt\nnew file mode 100644\n--- /dev/null\n+++ b/sub/file.txt\n@@ -0,0 +1 @@\n+evil\n"
+	if err := applyPatch(t, target, []byte(patchText)); err == nil || !strings.Contains(err.Error(), "beyond a symbolic link") {
+		t.Errorf("Expected an error for a path beyond a symlink, but got %v", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("Expected nothing to be written through the symlink, but got %v", entries)
+	}
+}
+
+func TestApplyPatch_GitFormats(t *testing.T) {
+	target := t.TempDir()
+	testutil.WriteFiles(t, target, map[string]string{
+		"old name.txt": "one\ntwo\nthree\n",
+		"copied.txt":   "copy\n",
+		"plain.txt":    "plain\n",
+	})
+
+	// a plain diff, and a rename and a copy as written by "git diff -M -C"
+	patch := `commit message, ignored
+
+--- a/plain.txt	2026-01-01 00:00:00
++++ b/plain.txt	2026-01-02 00:00:00
+@@ -1 +1 @@
+-plain
++PLAIN
+diff --git "a/old name.txt" "b/new\303\244.txt"
+similarity index 80%
+rename from old name.txt
+rename to "new\303\244.txt"
+index 4cb29ea..f2ad6c7 100644
+--- a/old name.txt	
++++ "b/new\303\244.txt"
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+diff --git a/copied.txt b/copy.txt
+similarity index 100%
+copy from copied.txt
+copy to copy.txt
+`
+	if err := applyPatch(t, target, []byte(patch)); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+
+	want := t.TempDir()
+	testutil.WriteFiles(t, want, map[string]string{
+		"newä.txt":   "one\nTWO\nthree\n",
+		"copied.txt": "copy\n",
+		"copy.txt":   "copy\n",
+		"plain.txt":  "PLAIN\n",
+	})
+	assertSameTree(t, target, want)
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	// the first file of a patch applies, but nothing is written if another
+	// one does not
+	const first = "--- /dev/null\n+++ b/first.txt\n@@ -0,0 +1 @@\n+first\n"
+
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+	}{
+		{
+			name:    "empty",
+			patch:   "no patch here\n",
+			wantErr: "no file changes found",
+		},
+		{
+			name:    "outside of the directory",
+			patch:   first + "--- a/../evil.txt\n+++ b/../evil.txt\n@@ -0,0 +1 @@\n+evil\n",
+			wantErr: "must be a relative path",
+		},
+		{
+			name:    "context mismatch",
+			patch:   first + "--- a/keep.txt\n+++ b/keep.txt\n@@ -1 +1 @@\n-other\n+new\n",
+			wantErr: "patch failed: keep.txt:1",
+		},
+		{
+			name:    "missing file",
+			patch:   first + "--- a/missing.txt\n+++ b/missing.txt\n@@ -1 +1 @@\n-old\n+new\n",
+			wantErr: "missing.txt: does not exist",
+		},
+		{
+			name:    "existing file",
+			patch:   first + "--- /dev/null\n+++ b/keep.txt\n@@ -0,0 +1 @@\n+new\n",
+			wantErr: "keep.txt: already exists",
+		},
+		{
+			name:    "truncated hunk",
+			patch:   first + "--- a/keep.txt\n+++ b/keep.txt\n@@ -1,2 +1,2 @@\n-keep\n",
+			wantErr: "is truncated",
+		},
+		{
+			name:    "binary without data",
+			patch:   first + "diff --git a/keep.txt b/keep.txt\nindex 1..2 100644\nBinary files a/keep.txt and b/keep.txt differ\n",
+			wantErr: "has no binary data",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := t.TempDir()
+			testutil.WriteFiles(t, target, map[string]string{"keep.txt": "keep\n"})
+
+			err := applyPatch(t, target, []byte(test.patch))
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Expected an error containing %q, but got %v", test.wantErr, err)
+			}
+			if _, err := os.Stat(filepath.Join(target, "first.txt")); !os.IsNotExist(err) {
+				t.Errorf("Expected a failed patch to change nothing, but first.txt exists: %v", err)
+			}
+		})
+	}
+}
+
+// TestGitCompatibility checks that patches of "git diff" apply with
+// ApplyPatch, and patches of Diff with "git apply".
+func TestGitCompatibility(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skipf("skip: git binary not available: %v", err)
+	}
+
+	// a large binary file, which git encodes as a delta
+	binary := bytes.Repeat([]byte("\x00binary data "), 1000)
+	upstream := map[string]string{
+		"file.txt":   "one\ntwo\nthree\n",
+		"binary.bin": string(binary),
+		"gone.txt":   "gone\n",
+	}
+	modified := map[string]string{
+		"file.txt":   "one\n2\nthree\nfour",
+		"binary.bin": string(binary) + "more",
+		"added.txt":  "added\n",
+	}
+
+	root := t.TempDir()
+	testutil.WriteFiles(t, filepath.Join(root, "a"), upstream)
+	testutil.WriteFiles(t, filepath.Join(root, "b"), modified)
+
+	gitPatch := &bytes.Buffer{}
+	err := runLocalGitCmd(t.Context(), root, gitPatch, "diff", "--no-index", "--binary", "--no-prefix", "--", "a", "b")
+	if exitErr := (*exec.ExitError)(nil); err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("git diff: %v", err)
+	}
+	if !strings.Contains(gitPatch.String(), "delta ") {
+		t.Logf("Expected git to write a delta:\n%s", gitPatch)
+	}
+
+	target := filepath.Join(t.TempDir(), "content")
+	testutil.WriteFiles(t, target, upstream)
+	if err := applyPatch(t, target, gitPatch.Bytes()); err != nil {
+		t.Fatalf("ApplyPatch of the git patch: %v\n%s", err, gitPatch)
+	}
+	assertSameTree(t, target, filepath.Join(root, "b"))
+
+	patch := &bytes.Buffer{}
+	if _, err := Diff(t.Context(), root, "a", "b", patch); err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	patchPath := filepath.Join(t.TempDir(), "local.patch")
+	if err := os.WriteFile(patchPath, patch.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target = filepath.Join(t.TempDir(), "content")
+	testutil.WriteFiles(t, target, upstream)
+	if err := runLocalGitCmd(t.Context(), target, nil, "apply", "--", patchPath); err != nil {
+		t.Fatalf("git apply of the patch of Diff: %v\n%s", err, patch)
+	}
+	assertSameTree(t, target, filepath.Join(root, "b"))
+}
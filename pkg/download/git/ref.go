@@ -0,0 +1,172 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// ResolvedRef is the result of resolving a repo_ref.
+type ResolvedRef struct {
+	// Hash is the commit hash the ref points to.
+	Hash string
+	// Tag is the tag that was selected, if the ref is a version constraint
+	// or a tag glob.
+	Tag string
+}
+
+// IsTagSelector reports whether ref selects a tag from the list of remote
+// tags, instead of naming a branch, tag or commit directly. Semver
+// constraints start with an operator (e.g. "~1.4", "^1.2.0" or
+// ">=2.0.0 <3.0.0"), tag globs contain a wildcard (e.g. "v1.*").
+func IsTagSelector(ref string) bool {
+	return isConstraint(ref) || isTagGlob(ref)
+}
+
+func isConstraint(ref string) bool {
+	return strings.ContainsAny(ref[:min(len(ref), 1)], "~^<>=!") || strings.Contains(ref, "||")
+}
+
+func isTagGlob(ref string) bool {
+	return strings.ContainsAny(ref, "*?[")
+}
+
+// ResolveRef resolves ref to a commit hash. If ref is a semver constraint,
+// the highest released version among the remote tags that satisfies it is
+// selected. If ref is a tag glob, the highest matching tag is selected,
+// comparing tags as semver versions where possible. Any other ref is
+// resolved with GetHash.
+func ResolveRef(ctx context.Context, repoURL string, ref string) (ResolvedRef, error) {
+	if !IsTagSelector(ref) {
+		hash, err := GetHash(ctx, repoURL, ref)
+		if err != nil {
+			return ResolvedRef{}, err
+		}
+		return ResolvedRef{Hash: hash}, nil
+	}
+
+	tags, err := listTags(ctx, repoURL)
+	if err != nil {
+		return ResolvedRef{}, err
+	}
+
+	tag, err := selectTag(ref, tags)
+	if err != nil {
+		return ResolvedRef{}, fmt.Errorf("%s@%s: %w", config.RedactURL(repoURL), ref, err)
+	}
+
+	return ResolvedRef{Hash: tags[tag], Tag: tag}, nil
+}
+
+// listTags returns the tags of the remote repository, mapped to the hash of
+// the commit they point to.
+func listTags(ctx context.Context, repoURL string) (map[string]string, error) {
+	remote, err := remoteURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := backendFromConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteRefs, err := backend.ListRefs(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := peelRefs(remoteRefs)
+
+	tags := make(map[string]string, len(refs))
+	for name, hash := range refs {
+		if tag, ok := strings.CutPrefix(name, "refs/tags/"); ok {
+			tags[tag] = hash
+		}
+	}
+
+	return tags, nil
+}
+
+// selectTag returns the highest tag that matches selector.
+func selectTag(selector string, tags map[string]string) (string, error) {
+	var (
+		constraint *semver.Constraints
+		err        error
+	)
+	if isConstraint(selector) {
+		constraint, err = semver.NewConstraint(selector)
+		if err != nil {
+			return "", fmt.Errorf("invalid version constraint %q: %w", selector, err)
+		}
+	} else if _, err := path.Match(selector, ""); err != nil {
+		return "", fmt.Errorf("invalid tag glob %q: %w", selector, err)
+	}
+
+	var (
+		bestTag     string
+		bestVersion *semver.Version
+	)
+	for tag := range tags {
+		version, versionErr := semver.NewVersion(tag)
+
+		if constraint != nil {
+			if versionErr != nil || !constraint.Check(version) {
+				continue
+			}
+		} else if ok, _ := path.Match(selector, tag); !ok {
+			continue
+		}
+
+		if isHigherTag(tag, version, bestTag, bestVersion) {
+			bestTag, bestVersion = tag, version
+		}
+	}
+
+	if bestTag == "" {
+		return "", fmt.Errorf("no tag matches %q", selector)
+	}
+
+	return bestTag, nil
+}
+
+// isHigherTag orders tags by semver version, with tags that are not valid
+// versions ordered below all versions and compared as strings. Tags that
+// are equal as versions (e.g. "1.0.0" and "v1.0.0") are compared as strings
+// to make the selection deterministic.
+func isHigherTag(tag string, version *semver.Version, other string, otherVersion *semver.Version) bool {
+	switch {
+	case other == "":
+		return true
+	case version != nil && otherVersion == nil:
+		return true
+	case version == nil && otherVersion != nil:
+		return false
+	case version != nil && otherVersion != nil:
+		if cmp := version.Compare(otherVersion); cmp != 0 {
+			return cmp > 0
+		}
+	}
+	return tag > other
+}
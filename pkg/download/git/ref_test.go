@@ -0,0 +1,165 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestParseLsRemote(t *testing.T) {
+	out := `1111111111111111111111111111111111111111	refs/heads/main
+2222222222222222222222222222222222222222	refs/tags/v1.0.0
+3333333333333333333333333333333333333333	refs/tags/v1.0.0^{}
+4444444444444444444444444444444444444444	refs/tags/v1.1.0
+`
+	refs, err := parseLsRemote([]byte(out))
+	if err != nil {
+		t.Fatalf("parseLsRemote: %v", err)
+	}
+	if len(refs) != 4 || refs[2] != (RemoteRef{Name: "refs/tags/v1.0.0^{}", Hash: "3333333333333333333333333333333333333333"}) {
+		t.Errorf("parseLsRemote() = %v", refs)
+	}
+
+	got := peelRefs(refs)
+	want := map[string]string{
+		"refs/heads/main":  "1111111111111111111111111111111111111111",
+		"refs/tags/v1.0.0": "3333333333333333333333333333333333333333",
+		"refs/tags/v1.1.0": "4444444444444444444444444444444444444444",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("peelRefs() = %v, want %v", got, want)
+	}
+
+	if _, err := parseLsRemote([]byte("garbage")); err == nil {
+		t.Errorf("parseLsRemote(garbage) = nil error, want error")
+	}
+}
+
+func TestSelectTag(t *testing.T) {
+	tags := map[string]string{
+		"v1.3.9":         "a",
+		"v1.4.0":         "b",
+		"v1.4.2":         "c",
+		"v1.5.0-alpha.1": "d",
+		"v1.5.0":         "e",
+		"v2.0.0":         "f",
+		"v2.1.3":         "g",
+		"v3.0.0-beta.0":  "h",
+		"latest":         "i",
+		"release-1.4":    "j",
+	}
+
+	tests := []struct {
+		selector string
+		want     string
+		errMatch string
+	}{
+		{selector: "~1.4", want: "v1.4.2"},
+		{selector: "^1.4", want: "v1.5.0"},
+		{selector: ">=2.0.0 <3.0.0", want: "v2.1.3"},
+		{selector: ">=2.0.0", want: "v2.1.3"},
+		{selector: ">=3.0.0-0", want: "v3.0.0-beta.0"},
+		{selector: "v1.*", want: "v1.5.0"},
+		{selector: "release-*", want: "release-1.4"},
+		{selector: "~4", errMatch: "no tag matches"},
+		{selector: ">=foo", errMatch: "invalid version constraint"},
+		{selector: "v[1", errMatch: "invalid tag glob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := selectTag(tt.selector, tags)
+			if tt.errMatch != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+					t.Errorf("selectTag(%q) = %q, %v; want error containing %q", tt.selector, got, err, tt.errMatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectTag(%q) returned unexpected error: %v", tt.selector, err)
+			}
+			if got != tt.want {
+				t.Errorf("selectTag(%q) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTagSelector(t *testing.T) {
+	for ref, want := range map[string]bool{
+		"main":           false,
+		"v1.4.0":         false,
+		"release-1.4":    false,
+		"":               false,
+		"~1.4":           true,
+		"^2":             true,
+		">=2.0.0 <3.0.0": true,
+		"1.2.x || ~2.0":  true,
+		"v1.*":           true,
+		"release-1.[45]": true,
+	} {
+		if got := IsTagSelector(ref); got != want {
+			t.Errorf("IsTagSelector(%q) = %v, want %v", ref, got, want)
+		}
+	}
+}
+
+func TestResolveRef_AnnotatedTags(t *testing.T) {
+	repo, git := testutil.NewGitRepo(t)
+	git("commit", "-q", "--allow-empty", "-m", "one")
+	git("tag", "-a", "-m", "v1.0.0", "v1.0.0")
+	first := git("rev-parse", "HEAD")
+	firstTag := git("rev-parse", "v1.0.0")
+	git("commit", "-q", "--allow-empty", "-m", "two")
+	git("tag", "-a", "-m", "v1.1.0", "v1.1.0")
+	second := git("rev-parse", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "three")
+	git("tag", "v2.0.0")
+	third := git("rev-parse", "HEAD")
+
+	tests := []struct {
+		ref  string
+		want ResolvedRef
+	}{
+		{ref: "main", want: ResolvedRef{Hash: third}},
+		// refs that are not selectors resolve as before, to the tag object
+		{ref: "v1.0.0", want: ResolvedRef{Hash: firstTag}},
+		// selectors resolve annotated tags to the commit they point to
+		{ref: "~1.0", want: ResolvedRef{Hash: first, Tag: "v1.0.0"}},
+		{ref: "^1.0", want: ResolvedRef{Hash: second, Tag: "v1.1.0"}},
+		{ref: ">=1.0.0", want: ResolvedRef{Hash: third, Tag: "v2.0.0"}},
+	}
+	for _, backend := range []string{BackendExec, BackendGoGit} {
+		t.Run(backend, func(t *testing.T) {
+			t.Setenv("KLONE_GIT_BACKEND", backend)
+
+			for _, tt := range tests {
+				got, err := ResolveRef(t.Context(), repo, tt.ref)
+				if err != nil {
+					t.Fatalf("ResolveRef(%q): %v", tt.ref, err)
+				}
+				if got != tt.want {
+					t.Errorf("ResolveRef(%q) = %+v, want %+v", tt.ref, got, tt.want)
+				}
+			}
+		})
+	}
+}
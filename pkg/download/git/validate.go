@@ -19,6 +19,8 @@ package git
 import (
 	"fmt"
 	"strings"
+
+	"github.com/cert-manager/klone/pkg/config"
 )
 
 // validateRepoURL rejects repo_url values that could be re-interpreted by git
@@ -28,10 +30,10 @@ func validateRepoURL(repoURL string) error {
 		return fmt.Errorf("repo_url is empty")
 	}
 	if strings.HasPrefix(repoURL, "-") {
-		return fmt.Errorf("repo_url %q starts with '-', refusing to pass it to git", repoURL)
+		return fmt.Errorf("repo_url %q starts with '-', refusing to pass it to git", config.RedactURL(repoURL))
 	}
 	if strings.Contains(repoURL, "::") {
-		return fmt.Errorf("repo_url %q uses a helper transport ('::'), which is not allowed", repoURL)
+		return fmt.Errorf("repo_url %q uses a helper transport ('::'), which is not allowed", config.RedactURL(repoURL))
 	}
 	switch {
 	case strings.HasPrefix(repoURL, "https://"),
@@ -47,5 +49,5 @@ func validateRepoURL(repoURL string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("repo_url %q does not use an allowed scheme (https/http/ssh/git/file/local path/scp-like)", repoURL)
+	return fmt.Errorf("repo_url %q does not use an allowed scheme (https/http/ssh/git/file/local path/scp-like)", config.RedactURL(repoURL))
 }
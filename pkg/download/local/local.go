@@ -0,0 +1,117 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/logging"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// ResolveRef returns the "h1:" hash of the directory src.RepoPath of
+// src.LocalDir(), which is the version a local source is pinned to.
+func ResolveRef(ctx context.Context, src mod.KloneSource) (string, error) {
+	if err := validateSource(src); err != nil {
+		return "", err
+	}
+
+	tempDir, err := os.MkdirTemp("", "klone-local-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tempDir)
+
+	// the hash is computed over a copy, so that it covers exactly what Get
+	// copies (e.g. without symlinks that point outside of the directory)
+	_, hash, err := copyDir(ctx, tempDir, src)
+	return hash, err
+}
+
+// Get copies the directory src.RepoPath of src.LocalDir() to targetPath
+// and returns the path of src.RepoPath in it. It fails if the content no
+// longer matches the hash in src.RepoHash.
+func Get(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
+	if err := validateSource(src); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(src.RepoHash, "h1:") {
+		return "", fmt.Errorf("invalid repo_hash %q for local_path %q: must be an \"h1:\" hash", src.RepoHash, src.LocalPath)
+	}
+
+	logging.FromContext(ctx).Info("copying", "local_path", src.LocalDir(), "repo_path", src.RepoPath, "hash", src.RepoHash, "dir", targetPath)
+
+	outPath, hash, err := copyDir(ctx, targetPath, src)
+	if err != nil {
+		return "", err
+	}
+
+	if hash != src.RepoHash {
+		return "", ChangedError{LocalPath: src.LocalPath, Pinned: src.RepoHash, Current: hash}
+	}
+
+	return outPath, nil
+}
+
+// ChangedError is returned when the content of a local path no longer
+// matches the hash it was pinned to.
+type ChangedError struct {
+	LocalPath string
+	Pinned    string
+	Current   string
+}
+
+func (e ChangedError) Error() string {
+	return fmt.Sprintf("local_path %q has changed since it was pinned to %s (now %s), run \"klone upgrade\" to pin its current content", e.LocalPath, e.Pinned, e.Current)
+}
+
+func copyDir(ctx context.Context, targetPath string, src mod.KloneSource) (string, string, error) {
+	srcPath := filepath.Join(src.LocalDir(), src.RepoPath)
+	if info, err := os.Stat(srcPath); err != nil || !info.IsDir() {
+		return "", "", fmt.Errorf("%s is not a directory in local_path %q", src.RepoPath, src.LocalPath)
+	}
+
+	outPath := filepath.Join(targetPath, src.RepoPath)
+	if _, err := cache.SyncTree(ctx, srcPath, outPath); err != nil {
+		return "", "", err
+	}
+
+	// like for git sources, the .git folder is never kloned; remove it now
+	// so that it does not change the hash
+	if err := os.RemoveAll(filepath.Join(outPath, ".git")); err != nil {
+		return "", "", err
+	}
+
+	hash, err := cache.HashTree(outPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	return outPath, hash, nil
+}
+
+func validateSource(src mod.KloneSource) error {
+	if src.RepoURL != "" || src.RepoRef != "" || src.ArchiveURL != "" || src.SHA256 != "" {
+		return fmt.Errorf("local_path %q cannot be combined with repo_url, repo_ref, archive_url or sha256", src.LocalPath)
+	}
+	return nil
+}
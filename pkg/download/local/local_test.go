@@ -0,0 +1,99 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestResolveRefAndGet(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{"config/a.yaml": "a"})
+	src := mod.KloneSource{LocalPath: dir, RepoPath: "config"}
+
+	hash, err := ResolveRef(t.Context(), src)
+	if err != nil {
+		t.Fatalf("ResolveRef: %v", err)
+	}
+	if !strings.HasPrefix(hash, "h1:") {
+		t.Fatalf("Expected an h1 hash, but got %q", hash)
+	}
+
+	// a .git folder is never kloned, so it does not change the hash
+	testutil.WriteFiles(t, dir, map[string]string{"config/.git/HEAD": "ref: refs/heads/main"})
+	if got, err := ResolveRef(t.Context(), src); err != nil || got != hash {
+		t.Errorf("Expected the .git folder to be ignored, but got %q, %v", got, err)
+	}
+
+	src.RepoHash = hash
+	outPath, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), src)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(outPath, "a.yaml")); err != nil || string(got) != "a" {
+		t.Errorf("a.yaml = %q, %v, want %q", got, err, "a")
+	}
+
+	testutil.WriteFiles(t, dir, map[string]string{"config/a.yaml": "changed"})
+	var changed ChangedError
+	if _, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), src); !errors.As(err, &changed) {
+		t.Errorf("Expected Get to fail with a ChangedError, but got %v", err)
+	}
+}
+
+func TestGet_Errors(t *testing.T) {
+	dir := t.TempDir()
+	testutil.WriteFiles(t, dir, map[string]string{"config/a.yaml": "a"})
+
+	tests := []struct {
+		name     string
+		src      mod.KloneSource
+		errMatch string
+	}{
+		{
+			name:     "combined with repo_url",
+			src:      mod.KloneSource{LocalPath: dir, RepoURL: "https://github.com/repo", RepoHash: "h1:x", RepoPath: "config"},
+			errMatch: "cannot be combined",
+		},
+		{
+			name:     "not a content hash",
+			src:      mod.KloneSource{LocalPath: dir, RepoHash: "abc", RepoPath: "config"},
+			errMatch: "must be an \"h1:\" hash",
+		},
+		{
+			name:     "missing repo_path",
+			src:      mod.KloneSource{LocalPath: dir, RepoHash: "h1:x", RepoPath: "other"},
+			errMatch: "is not a directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+				t.Errorf("Get() error = %v, want substring %q", err, tt.errMatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/download/archive"
+	"github.com/cert-manager/klone/pkg/logging"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// annotationTitle is the file name of a layer that is not a tar archive, as
+// set by e.g. "oras push".
+const annotationTitle = "org.opencontainers.image.title"
+
+// ResolveRef returns the digest of the manifest that the tag repoRef of the
+// OCI repository repoURL refers to.
+func ResolveRef(ctx context.Context, repoURL string, repoRef string) (string, error) {
+	ref, err := parseReference(repoURL)
+	if err != nil {
+		return "", err
+	}
+	if err := validateTag(repoURL, repoRef); err != nil {
+		return "", err
+	}
+
+	client := &registryClient{ref: ref}
+	_, digest, err := client.getManifest(ctx, repoRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s:%s: %w", repoURL, repoRef, err)
+	}
+
+	return digest, nil
+}
+
+// Get pulls the artifact with the manifest digest src.RepoHash, extracts
+// its layers to targetPath and returns the path of src.RepoPath in it.
+//
+// Tar layers (optionally gzip compressed) are extracted in order and must
+// not overlap; whiteout files are not supported. Any other layer is written
+// as a file named by its "org.opencontainers.image.title" annotation.
+func Get(ctx context.Context, targetPath string, src mod.KloneSource) (string, error) {
+	ref, err := parseReference(src.RepoURL)
+	if err != nil {
+		return "", err
+	}
+	if err := validateDigest(src.RepoURL, src.RepoHash); err != nil {
+		return "", err
+	}
+
+	logging.FromContext(ctx).Info("pulling", "repo_url", config.RedactURL(src.RepoURL), "repo_path", src.RepoPath, "digest", src.RepoHash, "dir", targetPath)
+
+	client := &registryClient{ref: ref}
+	m, _, err := client.getManifest(ctx, src.RepoHash)
+	if err != nil {
+		return "", fmt.Errorf("failed to pull %s@%s: %w", config.RedactURL(src.RepoURL), src.RepoHash, err)
+	}
+
+	if err := os.MkdirAll(targetPath, 0o755); err != nil {
+		return "", err
+	}
+
+	unpacker := archive.NewUnpacker(targetPath, 0)
+	for _, layer := range m.Layers {
+		if err := pullLayer(ctx, client, layer, unpacker); err != nil {
+			return "", fmt.Errorf("failed to pull layer %s of %s@%s: %w", layer.Digest, config.RedactURL(src.RepoURL), src.RepoHash, err)
+		}
+	}
+	if err := unpacker.Close(); err != nil {
+		return "", err
+	}
+
+	// a symlink in a layer must not redirect repo_path out of targetPath
+	if err := cache.AssertNoSymlinkInSubpath(targetPath, src.RepoPath); err != nil {
+		return "", fmt.Errorf("invalid repo_path %q in %s@%s: %w", src.RepoPath, config.RedactURL(src.RepoURL), src.RepoHash, err)
+	}
+
+	outPath := filepath.Join(targetPath, src.RepoPath)
+	if info, err := os.Lstat(outPath); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory in %s@%s", src.RepoPath, config.RedactURL(src.RepoURL), src.RepoHash)
+	}
+
+	return outPath, nil
+}
+
+func pullLayer(ctx context.Context, client *registryClient, layer descriptor, unpacker *archive.Unpacker) error {
+	if !digestRegexp.MatchString(layer.Digest) {
+		return fmt.Errorf("unsupported digest %q", layer.Digest)
+	}
+
+	// the blob is verified before anything is extracted from it
+	file, err := os.CreateTemp("", "klone-oci-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := client.getBlob(ctx, layer, file); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	mediaType := layer.MediaType
+	switch {
+	case strings.HasSuffix(mediaType, ".tar"):
+		return unpacker.Tar(file)
+	case strings.HasSuffix(mediaType, ".tar+gzip"), strings.HasSuffix(mediaType, ".tar.gzip"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+
+		return unpacker.Tar(gz)
+	case layer.Annotations[annotationTitle] != "":
+		return unpacker.File(layer.Annotations[annotationTitle], file, 0o644)
+	default:
+		return fmt.Errorf("unsupported media type %q without a %s annotation", mediaType, annotationTitle)
+	}
+}
@@ -0,0 +1,348 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// fakeRegistry serves the manifests and blobs of the repository "org/bundles"
+// like an OCI registry. If token is set, every request must carry it as a
+// bearer token, which is handed out by the /token endpoint.
+type fakeRegistry struct {
+	*httptest.Server
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	token     string
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	t.Helper()
+
+	r := &fakeRegistry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *fakeRegistry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if req.URL.Query().Get("scope") != "repository:org/bundles:pull" {
+			http.Error(w, "invalid scope", http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": r.token})
+		return
+	}
+
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.URL+`/token",service="fake"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path, ok := strings.CutPrefix(req.URL.Path, "/v2/org/bundles/")
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	if ref, ok := strings.CutPrefix(path, "manifests/"); ok {
+		content, ok := r.manifests[ref]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", mediaTypeOCIManifest)
+		_, _ = w.Write(content)
+		return
+	}
+
+	if digest, ok := strings.CutPrefix(path, "blobs/"); ok {
+		content, ok := r.blobs[digest]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(content)
+		return
+	}
+
+	http.NotFound(w, req)
+}
+
+func digestOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// push stores the layers and a manifest referring to them under tag and
+// returns the digest of the manifest.
+func (r *fakeRegistry) push(t *testing.T, tag string, mediaType string, layers ...descriptor) string {
+	t.Helper()
+
+	content, err := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     mediaType,
+		"layers":        layers,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := digestOf(content)
+	r.manifests[tag] = content
+	r.manifests[digest] = content
+	return digest
+}
+
+func (r *fakeRegistry) layer(mediaType string, content []byte, title string) descriptor {
+	desc := descriptor{MediaType: mediaType, Digest: digestOf(content), Size: int64(len(content))}
+	if title != "" {
+		desc.Annotations = map[string]string{annotationTitle: title}
+	}
+	r.blobs[desc.Digest] = content
+	return desc
+}
+
+func (r *fakeRegistry) repoURL() string {
+	return Scheme + strings.TrimPrefix(r.URL, "http://") + "/org/bundles"
+}
+
+func TestResolveRefAndGet(t *testing.T) {
+	for _, token := range []string{"", "secret"} {
+		t.Run("token="+token, func(t *testing.T) {
+			registry := newFakeRegistry(t)
+			registry.token = token
+
+			digest := registry.push(t, "v1.0.0", mediaTypeOCIManifest,
+				registry.layer("application/vnd.oci.image.layer.v1.tar+gzip", testutil.TarGz(t, []testutil.Entry{
+					{Name: "config/a.yaml", Content: "a"},
+					{Name: "config/nested/b.yaml", Content: "b"},
+				}), ""),
+				registry.layer("application/yaml", []byte("c"), "config/c.yaml"),
+			)
+
+			resolved, err := ResolveRef(t.Context(), registry.repoURL(), "v1.0.0")
+			if err != nil {
+				t.Fatalf("ResolveRef: %v", err)
+			}
+			if resolved != digest {
+				t.Errorf("ResolveRef() = %q, want %q", resolved, digest)
+			}
+
+			// a digest resolves to itself
+			if resolved, err := ResolveRef(t.Context(), registry.repoURL(), digest); err != nil || resolved != digest {
+				t.Errorf("ResolveRef(digest) = %q, %v, want %q", resolved, err, digest)
+			}
+
+			outPath, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), mod.KloneSource{
+				RepoURL:  registry.repoURL(),
+				RepoRef:  "v1.0.0",
+				RepoHash: digest,
+				RepoPath: "config",
+			})
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+
+			for name, want := range map[string]string{"a.yaml": "a", "nested/b.yaml": "b", "c.yaml": "c"} {
+				got, err := os.ReadFile(filepath.Join(outPath, name))
+				if err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGet_Errors(t *testing.T) {
+	registry := newFakeRegistry(t)
+
+	layer := registry.layer("application/vnd.oci.image.layer.v1.tar+gzip", testutil.TarGz(t, []testutil.Entry{{Name: "config/a.yaml", Content: "a"}}), "")
+	valid := registry.push(t, "valid", mediaTypeOCIManifest, layer)
+
+	tampered := registry.layer("application/vnd.oci.image.layer.v1.tar", []byte("original"), "")
+	registry.blobs[tampered.Digest] = []byte("tampered")
+	tamperedDigest := registry.push(t, "tampered", mediaTypeOCIManifest, tampered)
+
+	index := registry.push(t, "index", mediaTypeOCIIndex)
+
+	unknown := registry.push(t, "unknown", mediaTypeOCIManifest,
+		registry.layer("application/octet-stream", []byte("x"), ""))
+
+	traversal := registry.push(t, "traversal", mediaTypeOCIManifest,
+		registry.layer("application/yaml", []byte("x"), "../evil.yaml"))
+
+	// a symlink to a directory outside of the target that has the repo_path
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(outside, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "x", Typeflag: tar.TypeSymlink, Linkname: outside}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	escape := registry.push(t, "escape", mediaTypeOCIManifest,
+		registry.layer("application/vnd.oci.image.layer.v1.tar", buf.Bytes(), ""))
+
+	tests := []struct {
+		name     string
+		src      mod.KloneSource
+		errMatch string
+	}{
+		{
+			name:     "missing repo_path",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: valid, RepoPath: "other"},
+			errMatch: "is not a directory",
+		},
+		{
+			name:     "tampered blob",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: tamperedDigest, RepoPath: "."},
+			errMatch: "digest mismatch for blob",
+		},
+		{
+			name:     "image index",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: index, RepoPath: "."},
+			errMatch: "is an image index",
+		},
+		{
+			name:     "unknown layer media type",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: unknown, RepoPath: "."},
+			errMatch: "unsupported media type",
+		},
+		{
+			name:     "traversal in title",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: traversal, RepoPath: "."},
+			errMatch: "traversal segment",
+		},
+		{
+			name:     "symlink in repo_path",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: escape, RepoPath: "x/sub"},
+			errMatch: "refusing to traverse symlink",
+		},
+		{
+			name:     "unknown digest",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: digestOf([]byte("missing")), RepoPath: "."},
+			errMatch: "404",
+		},
+		{
+			name:     "tag instead of digest",
+			src:      mod.KloneSource{RepoURL: registry.repoURL(), RepoHash: "valid", RepoPath: "."},
+			errMatch: "must be a sha256 digest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+				t.Errorf("Get() error = %v, want substring %q", err, tt.errMatch)
+			}
+		})
+	}
+}
+
+func TestGet_ManifestDigestMismatch(t *testing.T) {
+	registry := newFakeRegistry(t)
+
+	digest := registry.push(t, "v1", mediaTypeOCIManifest)
+	registry.manifests[digest] = registry.manifests[registry.push(t, "v2", mediaTypeDockerManifest)]
+
+	_, err := Get(t.Context(), filepath.Join(t.TempDir(), "target"), mod.KloneSource{
+		RepoURL:  registry.repoURL(),
+		RepoHash: digest,
+		RepoPath: ".",
+	})
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("Get() error = %v, want a digest mismatch", err)
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		input    string
+		want     reference
+		wantBase string
+		errMatch string
+	}{
+		{input: "oci://ghcr.io/org/bundles", want: reference{registry: "ghcr.io", repository: "org/bundles"}, wantBase: "https://ghcr.io"},
+		{input: "oci://localhost:5000/bundles", want: reference{registry: "localhost:5000", repository: "bundles"}, wantBase: "http://localhost:5000"},
+		{input: "oci://127.0.0.1:5000/a/b-c_d.e", want: reference{registry: "127.0.0.1:5000", repository: "a/b-c_d.e"}, wantBase: "http://127.0.0.1:5000"},
+		{input: "https://ghcr.io/org/bundles", errMatch: "must start with"},
+		{input: "oci://ghcr.io", errMatch: "must be oci://registry/repository"},
+		{input: "oci://ghcr.io/org/bundles:v1", errMatch: "must be set in repo_ref"},
+		{input: "oci://ghcr.io/org/bundles@sha256:abc", errMatch: "must be set in repo_ref"},
+		{input: "oci://ghcr.io/Org/Bundles", errMatch: "invalid repository name"},
+		{input: "oci://ghcr.io/org/../bundles", errMatch: "invalid repository name"},
+		{input: "oci://user@ghcr.io/org/bundles", errMatch: "must be a host"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseReference(tt.input)
+		if tt.errMatch != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+				t.Errorf("parseReference(%q) error = %v, want substring %q", tt.input, err, tt.errMatch)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseReference(%q) returned unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseReference(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+		if base := got.baseURL(); base != tt.wantBase {
+			t.Errorf("baseURL() of %q = %q, want %q", tt.input, base, tt.wantBase)
+		}
+	}
+}
+
+func TestParseChallengeParams(t *testing.T) {
+	got := parseChallengeParams(`realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull,push",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseChallengeParams() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseChallengeParams()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// Scheme is the repo_url scheme of OCI sources.
+const Scheme = "oci://"
+
+var (
+	// repositoryRegexp matches the repository name grammar of the OCI
+	// distribution spec.
+	repositoryRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:\.|_|__|-+)[a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// reference is a parsed "oci://registry/repository" repo_url.
+type reference struct {
+	registry   string
+	repository string
+}
+
+// IsReference reports whether repoURL refers to an OCI repository.
+func IsReference(repoURL string) bool {
+	return strings.HasPrefix(repoURL, Scheme)
+}
+
+func parseReference(repoURL string) (reference, error) {
+	rest, ok := strings.CutPrefix(repoURL, Scheme)
+	if !ok {
+		return reference{}, fmt.Errorf("invalid repo_url %q: must start with %q", config.RedactURL(repoURL), Scheme)
+	}
+
+	registry, repository, ok := strings.Cut(rest, "/")
+	if !ok || registry == "" {
+		return reference{}, fmt.Errorf("invalid repo_url %q: must be %sregistry/repository", config.RedactURL(repoURL), Scheme)
+	}
+	if strings.ContainsAny(registry, "@?#") {
+		return reference{}, fmt.Errorf("invalid repo_url %q: registry %q must be a host and optional port", config.RedactURL(repoURL), registry)
+	}
+	if strings.ContainsAny(repository, ":@") {
+		return reference{}, fmt.Errorf("invalid repo_url %q: the tag or digest must be set in repo_ref", config.RedactURL(repoURL))
+	}
+	if !repositoryRegexp.MatchString(repository) {
+		return reference{}, fmt.Errorf("invalid repo_url %q: invalid repository name %q", config.RedactURL(repoURL), repository)
+	}
+
+	return reference{registry: registry, repository: repository}, nil
+}
+
+// baseURL returns the URL of the registry API. Like docker, plain http is
+// only used for registries on the loopback interface.
+func (r reference) baseURL() string {
+	host := r.registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		return "http://" + r.registry
+	}
+	return "https://" + r.registry
+}
+
+func validateTag(repoURL string, tag string) error {
+	if !tagRegexp.MatchString(tag) && !digestRegexp.MatchString(tag) {
+		return fmt.Errorf("invalid repo_ref %q for %s: must be a tag or a sha256 digest", tag, config.RedactURL(repoURL))
+	}
+	return nil
+}
+
+func validateDigest(repoURL string, digest string) error {
+	if !digestRegexp.MatchString(digest) {
+		return fmt.Errorf("invalid repo_hash %q for %s: must be a sha256 digest", digest, config.RedactURL(repoURL))
+	}
+	return nil
+}
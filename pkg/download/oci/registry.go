@@ -0,0 +1,241 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// maxManifestSize is the size limit of manifests recommended by the
+	// OCI distribution spec.
+	maxManifestSize = 4 << 20
+)
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// registryClient pulls from a single repository of an OCI registry. Like
+// "docker pull", it fetches an anonymous bearer token if the registry asks
+// for one.
+type registryClient struct {
+	ref   reference
+	token string
+}
+
+// getManifest returns the manifest tagOrDigest refers to and its digest.
+func (c *registryClient) getManifest(ctx context.Context, tagOrDigest string) (manifest, string, error) {
+	resp, err := c.get(ctx, "manifests/"+tagOrDigest, strings.Join([]string{
+		mediaTypeOCIManifest, mediaTypeDockerManifest, mediaTypeOCIIndex, mediaTypeDockerList,
+	}, ", "))
+	if err != nil {
+		return manifest{}, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return manifest{}, "", err
+	}
+	if len(body) > maxManifestSize {
+		return manifest{}, "", fmt.Errorf("manifest %s is larger than %d bytes", tagOrDigest, maxManifestSize)
+	}
+
+	// the digest is computed, never taken from the Docker-Content-Digest
+	// header, so that a registry cannot serve content for another digest
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(tagOrDigest, "sha256:") && digest != tagOrDigest {
+		return manifest{}, "", fmt.Errorf("digest mismatch for manifest %s: got %s", tagOrDigest, digest)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return manifest{}, "", fmt.Errorf("failed to parse manifest %s: %w", tagOrDigest, err)
+	}
+
+	mediaType := m.MediaType
+	if mediaType == "" {
+		mediaType = resp.Header.Get("Content-Type")
+	}
+	switch mediaType {
+	case mediaTypeOCIManifest, mediaTypeDockerManifest:
+	case mediaTypeOCIIndex, mediaTypeDockerList:
+		return manifest{}, "", fmt.Errorf("%s is an image index, repo_ref must refer to a single artifact", tagOrDigest)
+	default:
+		return manifest{}, "", fmt.Errorf("%s has unsupported manifest media type %q", tagOrDigest, mediaType)
+	}
+
+	return m, digest, nil
+}
+
+// getBlob writes the blob desc to w and verifies its size and digest.
+func (c *registryClient) getBlob(ctx context.Context, desc descriptor, w io.Writer) error {
+	resp, err := c.get(ctx, "blobs/"+desc.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), io.LimitReader(resp.Body, desc.Size+1))
+	if err != nil {
+		return fmt.Errorf("failed to download blob %s: %w", desc.Digest, err)
+	}
+	if n != desc.Size {
+		return fmt.Errorf("size mismatch for blob %s: want %d bytes, got %d", desc.Digest, desc.Size, n)
+	}
+	if got := "sha256:" + hex.EncodeToString(hash.Sum(nil)); got != desc.Digest {
+		return fmt.Errorf("digest mismatch for blob %s: got %s", desc.Digest, got)
+	}
+
+	return nil
+}
+
+func (c *registryClient) get(ctx context.Context, path string, accept string) (*http.Response, error) {
+	u := fmt.Sprintf("%s/v2/%s/%s", c.ref.baseURL(), c.ref.repository, path)
+
+	resp, err := c.do(ctx, u, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if c.token, err = c.fetchToken(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, u, accept); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s: %s", u, resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (c *registryClient) do(ctx context.Context, u string, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", u, err)
+	}
+	return resp, nil
+}
+
+// fetchToken requests an anonymous pull token for the repository from the
+// token server named in a "Bearer" WWW-Authenticate challenge.
+func (c *registryClient) fetchToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("registry %s requires unsupported authentication %q", c.ref.registry, scheme)
+	}
+
+	attrs := parseChallengeParams(params)
+	realm, err := url.Parse(attrs["realm"])
+	if err != nil || (realm.Scheme != "https" && realm.Scheme != "http") {
+		return "", fmt.Errorf("registry %s sent an invalid token realm %q", c.ref.registry, attrs["realm"])
+	}
+
+	query := realm.Query()
+	if service := attrs["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+c.ref.repository+":pull")
+	realm.RawQuery = query.Encode()
+
+	resp, err := c.do(ctx, realm.String(), "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a pull token for %s from %s: %s", c.ref.repository, realm.Host, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse the pull token for %s: %w", c.ref.repository, err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("registry %s returned an empty pull token", c.ref.registry)
+}
+
+// parseChallengeParams parses the comma separated key="value" pairs of a
+// WWW-Authenticate challenge.
+func parseChallengeParams(params string) map[string]string {
+	attrs := map[string]string{}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		attrs[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return attrs
+}
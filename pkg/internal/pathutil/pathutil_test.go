@@ -0,0 +1,41 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pathutil
+
+import "testing"
+
+func TestHasWindowsDrivePrefix(t *testing.T) {
+	tests := map[string]bool{
+		"C:":         true,
+		"c:/evil":    true,
+		`Z:\evil`:    true,
+		"C":          false,
+		"1:/evil":    false,
+		"a/b:c":      false,
+		"":           false,
+		"::":         false,
+		"folder":     false,
+		"./C:/evil":  false,
+		"ab:/c/d/e/": false,
+	}
+
+	for path, want := range tests {
+		if got := HasWindowsDrivePrefix(path); got != want {
+			t.Errorf("HasWindowsDrivePrefix(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testutil contains helpers that are shared by the tests of the
+// other packages. It must only be imported from tests.
+package testutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// WriteFiles writes files, a map of slash-separated paths relative to root
+// to their contents, creating their parent directories.
+func WriteFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+}
+
+// SkipIfNoSymlinks probes whether the current process/OS can create a
+// symlink and skips the test if it cannot. Windows without Developer Mode
+// (or sandboxed CI without the symlink privilege) returns EPERM/ENOTSUP
+// from os.Symlink, which would otherwise mask the real assertion under a
+// setup failure.
+func SkipIfNoSymlinks(t *testing.T) {
+	t.Helper()
+	probe := t.TempDir()
+	if err := os.Symlink(probe, filepath.Join(probe, "probe")); err != nil {
+		t.Skipf("skip: symlinks unsupported in this environment: %v", err)
+	}
+}
+
+// NewGitRepo creates an empty git repository with a "main" branch and
+// returns its path and a function that runs git commands in it. The test is
+// skipped if there is no git binary.
+func NewGitRepo(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skipf("skip: git binary not available: %v", err)
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.CommandContext(t.Context(), "git", append([]string{"-c", "user.name=klone", "-c", "user.email=klone@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q", "-b", "main")
+
+	return repo, git
+}
+
+// NewGitRepoWithFiles creates a git repository containing files in a single
+// commit and returns its path and the commit hash.
+func NewGitRepoWithFiles(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+
+	repo, git := NewGitRepo(t)
+	WriteFiles(t, repo, files)
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	return repo, git("rev-parse", "HEAD")
+}
+
+// Entry is a file, directory (Name ending in "/") or symlink (Link set) in
+// a test archive. Mode defaults to 0o644 for files and 0o755 for
+// directories.
+type Entry struct {
+	Name    string
+	Content string
+	Link    string
+	Mode    int64
+}
+
+// TarGz returns a gzip compressed tar archive of entries, in order.
+func TarGz(t *testing.T, entries []Entry) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.Name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(e.Content))}
+		switch {
+		case e.Link != "":
+			header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, e.Link, 0
+		case strings.HasSuffix(e.Name, "/"):
+			header.Typeflag, header.Mode = tar.TypeDir, 0o755
+		}
+		if e.Mode != 0 {
+			header.Mode = e.Mode
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.Content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(t.Context()); got != slog.Default() {
+		t.Errorf("Expected the default logger for a context without logger")
+	}
+
+	out := &bytes.Buffer{}
+	ctx := IntoContext(t.Context(), New(out, slog.LevelInfo))
+
+	FromContext(ctx).Debug("hidden")
+	FromContext(ctx).Info("cloning", "repo_url", "https://example.com/repo.git")
+
+	want := "level=INFO msg=cloning repo_url=https://example.com/repo.git\n"
+	if got := out.String(); got != want {
+		t.Errorf("Expected output %q, but got %q", want, got)
+	}
+}
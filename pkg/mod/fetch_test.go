@@ -0,0 +1,156 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writeKloneFile(t *testing.T, dir string, targets int, items int) {
+	t.Helper()
+
+	var b strings.Builder
+	b.WriteString("targets:\n")
+	for i := range targets {
+		fmt.Fprintf(&b, "  t%d:\n", i)
+		for j := range items {
+			fmt.Fprintf(&b, "    - folder_name: f%d\n      repo_url: https://github.com/repo%d\n      repo_ref: main\n      repo_path: path\n", j, j)
+		}
+	}
+
+	if err := os.WriteFile(path.Join(dir, kloneFileName), []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+}
+
+func TestFetchTargets_Jobs(t *testing.T) {
+	tempDirPath := t.TempDir()
+	writeKloneFile(t, tempDirPath, 3, 4)
+
+	var running, maxRunning atomic.Int32
+	var fetched []string
+	err := WorkDir(tempDirPath).FetchTargets(
+		FetchOptions{Jobs: 3},
+		func(target string, folderName string, src *KloneSource) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			src.RepoHash = target + "-" + folderName
+			return nil
+		},
+		func(target string, srcs KloneFolder, selected []bool) error {
+			if running.Load() != 0 {
+				t.Errorf("Expected fetchFn to be called after all cleanFn calls returned")
+			}
+			for _, src := range srcs {
+				fetched = append(fetched, src.RepoHash)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("FetchTargets returned error: %v", err)
+	}
+
+	if got := maxRunning.Load(); got < 2 || got > 3 {
+		t.Errorf("Expected between 2 and 3 concurrent cleanFn calls, but got %d", got)
+	}
+
+	var want []string
+	for i := range 3 {
+		for j := range 4 {
+			want = append(want, fmt.Sprintf("t%d-f%d", i, j))
+		}
+	}
+	if !slices.Equal(fetched, want) {
+		t.Errorf("Expected fetchFn to see the items in order %v, but got %v", want, fetched)
+	}
+}
+
+func TestFetchTargets_JobsErrorsOrdered(t *testing.T) {
+	tempDirPath := t.TempDir()
+	writeKloneFile(t, tempDirPath, 2, 3)
+
+	before := readFile(t, path.Join(tempDirPath, kloneFileName))
+
+	var mu sync.Mutex
+	var cleaned []string
+	err := WorkDir(tempDirPath).FetchTargets(
+		FetchOptions{Jobs: 4},
+		func(target string, folderName string, src *KloneSource) error {
+			mu.Lock()
+			cleaned = append(cleaned, target+"/"+folderName)
+			mu.Unlock()
+
+			src.RepoHash = "abc123"
+
+			// fail the later item first
+			switch target + "/" + folderName {
+			case "t0/f1":
+				time.Sleep(20 * time.Millisecond)
+				return errors.New("first failure")
+			case "t1/f2":
+				return errors.New("second failure")
+			}
+			return nil
+		},
+		func(target string, srcs KloneFolder, selected []bool) error {
+			t.Errorf("Expected fetchFn not to be called when cleanFn fails")
+			return nil
+		},
+	)
+	if err == nil {
+		t.Fatalf("Expected FetchTargets to return an error")
+	}
+
+	want := "t0/f1: first failure\nt1/f2: second failure"
+	if err.Error() != want {
+		t.Errorf("Expected error %q, but got %q", want, err.Error())
+	}
+
+	var failed []string
+	for _, itemErr := range ItemErrors(fmt.Errorf("wrapped: %w", err)) {
+		failed = append(failed, itemErr.Target+"/"+itemErr.FolderName)
+	}
+	if want := []string{"t0/f1", "t1/f2"}; !slices.Equal(failed, want) {
+		t.Errorf("Expected ItemErrors to return %v, but got %v", want, failed)
+	}
+
+	if len(cleaned) != 6 {
+		t.Errorf("Expected cleanFn to be called for all 6 items, but got %v", cleaned)
+	}
+
+	if after := readFile(t, path.Join(tempDirPath, kloneFileName)); after != before {
+		t.Errorf("Expected the klone file to be unchanged after an error, but got:\n%s", after)
+	}
+}
@@ -18,13 +18,19 @@ package mod
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/rogpeppe/go-internal/lockedfile"
 	"gopkg.in/yaml.v3"
+
+	"github.com/cert-manager/klone/pkg/config"
 )
 
 const kloneFileName = "klone.yaml"
@@ -33,6 +39,9 @@ type WorkDir string
 
 type kloneFile struct {
 	Targets map[string]KloneFolder `yaml:"targets"`
+	// Mirrors rewrite the repo_url of git items of this project before they
+	// are fetched, see config.Mirror.
+	Mirrors []config.Mirror `yaml:"mirrors,omitempty"`
 }
 
 func (f *kloneFile) canonicalize() {
@@ -62,25 +71,144 @@ func (f *kloneFile) canonicalize() {
 	f.Targets = newModTargets
 }
 
+// setBaseDir records the directory of the klone file in every item, so that
+// relative local paths can be resolved.
+func (f *kloneFile) setBaseDir(dir string) {
+	for _, srcs := range f.Targets {
+		for i := range srcs {
+			srcs[i].baseDir = dir
+		}
+	}
+}
+
 type KloneFolder []KloneItem
 
 type KloneItem struct {
 	FolderName  string `yaml:"folder_name"`
 	KloneSource `yaml:",inline"`
+	// Patches lists patch files, relative to the klone file, that are
+	// applied in order on top of the upstream content.
+	Patches []string `yaml:"patches,omitempty"`
 }
 
 func (i KloneItem) Compare(other KloneItem) int {
 	return strings.Compare(i.FolderName, other.FolderName)
 }
 
+// SourceKind is the kind of upstream a KloneSource is downloaded from.
+type SourceKind string
+
+const (
+	// SourceGit is a directory in a git repository, pinned by commit hash.
+	SourceGit SourceKind = "git"
+	// SourceArchive is a directory in a tar or zip archive downloaded over
+	// HTTP, pinned by the sha256 of the archive.
+	SourceArchive SourceKind = "archive"
+	// SourceOCI is a directory in the layers of an OCI artifact, pinned by
+	// the digest of its manifest.
+	SourceOCI SourceKind = "oci"
+	// SourceLocal is a directory on the local filesystem, pinned by the
+	// "h1:" hash of its content.
+	SourceLocal SourceKind = "local"
+)
+
 type KloneSource struct {
-	RepoURL  string `yaml:"repo_url"`
-	RepoRef  string `yaml:"repo_ref"`
+	// RepoURL is the URL of a git repository, or of an OCI repository if it
+	// starts with "oci://" (e.g. "oci://ghcr.io/org/bundles").
+	RepoURL string `yaml:"repo_url"`
+	// RepoRef is a branch, tag or commit, or a semver constraint (e.g.
+	// "~1.4") or tag glob (e.g. "v1.*") that selects the highest matching
+	// tag. For OCI repositories, it is a tag or digest.
+	RepoRef string `yaml:"repo_ref"`
+	// RepoHash is the commit hash, or the manifest digest for OCI
+	// repositories, that RepoRef resolved to.
 	RepoHash string `yaml:"repo_hash"`
+	// RepoTag is the tag that RepoRef resolved to, if RepoRef selects a tag.
+	RepoTag string `yaml:"repo_tag,omitempty"`
+	// ContentHash is the "h1:" hash of the kloned content, before patches
+	// are applied, recorded when RepoHash was pinned.
+	ContentHash string `yaml:"content_hash,omitempty"`
+
+	// ArchiveURL is the http(s) URL of a .tar, .tar.gz, .tgz or .zip
+	// archive. It is used instead of RepoURL and must be pinned with SHA256.
+	ArchiveURL string `yaml:"archive_url,omitempty"`
+	SHA256     string `yaml:"sha256,omitempty"`
+	// StripComponents removes this many leading path components from the
+	// paths in the archive, like tar's --strip-components.
+	StripComponents int `yaml:"strip_components,omitempty"`
+
+	// LocalPath is a directory on the local filesystem, absolute or
+	// relative to the klone file, that is copied instead of downloading a
+	// repository. Its RepoHash is the "h1:" hash of the copied content.
+	LocalPath string `yaml:"local_path,omitempty"`
+	// baseDir is the directory of the klone file, which a relative
+	// LocalPath is resolved against.
+	baseDir string
+
+	// RepoPath is the directory in the repository, archive or local path to
+	// klone.
 	RepoPath string `yaml:"repo_path"`
+
+	// Include and Exclude are glob patterns, relative to RepoPath, that
+	// limit the files that are kloned. "**" matches any number of path
+	// segments and a pattern that matches a directory applies to all of its
+	// contents. If Include is empty, all files are included.
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+func (s KloneSource) Kind() SourceKind {
+	if s.LocalPath != "" {
+		return SourceLocal
+	}
+	if s.ArchiveURL != "" {
+		return SourceArchive
+	}
+	if strings.HasPrefix(s.RepoURL, "oci://") {
+		return SourceOCI
+	}
+	return SourceGit
+}
+
+// Location returns the URL the source is downloaded from.
+func (s KloneSource) Location() string {
+	switch s.Kind() {
+	case SourceArchive:
+		return s.ArchiveURL
+	case SourceLocal:
+		return s.LocalPath
+	}
+	return s.RepoURL
+}
+
+// LocalDir returns the absolute path of LocalPath.
+func (s KloneSource) LocalDir() string {
+	if filepath.IsAbs(s.LocalPath) {
+		return filepath.Clean(s.LocalPath)
+	}
+	return filepath.Join(s.baseDir, s.LocalPath)
+}
+
+// Pin returns the version the source is pinned to, or "" if it has not been
+// pinned yet.
+func (s KloneSource) Pin() string {
+	if s.Kind() == SourceArchive {
+		if s.SHA256 == "" {
+			return ""
+		}
+		return "sha256:" + s.SHA256
+	}
+	return s.RepoHash
 }
 
 func (w WorkDir) editKloneFile(fn func(*kloneFile) error) error {
+	// the lock file is always locked before the klone file
+	return w.editLockFile(func(lock *lockFile) error {
+		return w.editKloneFileWithLock(lock, fn)
+	})
+}
+
+func (w WorkDir) editKloneFileWithLock(lock *lockFile, fn func(*kloneFile) error) error {
 	kloneFilePath := filepath.Join(string(w), kloneFileName)
 
 	// exclusively open or create index file
@@ -99,6 +227,12 @@ func (w WorkDir) editKloneFile(fn func(*kloneFile) error) error {
 
 	// canonicalize index
 	index.canonicalize()
+	index.setBaseDir(string(w))
+
+	// fill in hashes that are only stored in the lock file
+	if lock != nil {
+		lock.applyTo(&index)
+	}
 
 	// update index
 	if err := fn(&index); err != nil {
@@ -108,6 +242,11 @@ func (w WorkDir) editKloneFile(fn func(*kloneFile) error) error {
 	// canonicalize index
 	index.canonicalize()
 
+	// move all hashes to the lock file
+	if lock != nil {
+		lock.moveFrom(&index)
+	}
+
 	var topComments string
 
 	{
@@ -170,6 +309,70 @@ func (w WorkDir) editKloneFile(fn func(*kloneFile) error) error {
 	return nil
 }
 
+// ReadTargets returns the canonicalized targets listed in the klone file,
+// with the hashes from the lock file filled in, without modifying either.
+func (w WorkDir) ReadTargets() (map[string]KloneFolder, error) {
+	lock, err := w.readLockFile()
+	if err != nil {
+		return nil, err
+	}
+
+	kloneFilePath := filepath.Join(string(w), kloneFileName)
+
+	file, err := lockedfile.Open(kloneFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := kloneFile{}
+	if err := yaml.NewDecoder(file).Decode(&index); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	index.canonicalize()
+	index.setBaseDir(string(w))
+
+	if lock != nil {
+		lock.applyTo(&index)
+	}
+
+	return index.Targets, nil
+}
+
+// ReadMirrors returns the mirrors listed in the klone file.
+func (w WorkDir) ReadMirrors() ([]config.Mirror, error) {
+	file, err := lockedfile.Open(filepath.Join(string(w), kloneFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := kloneFile{}
+	if err := yaml.NewDecoder(file).Decode(&index); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	for _, mirror := range index.Mirrors {
+		if err := mirror.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", kloneFileName, err)
+		}
+	}
+
+	return index.Mirrors, nil
+}
+
+// viewKloneFile calls fn with the current contents of the klone file and
+// discards any changes made by fn.
+func (w WorkDir) viewKloneFile(fn func(*kloneFile) error) error {
+	targets, err := w.ReadTargets()
+	if err != nil {
+		return err
+	}
+
+	return fn(&kloneFile{Targets: targets})
+}
+
 func (w WorkDir) Init() error {
 	return w.editKloneFile(func(kf *kloneFile) error {
 		return nil
@@ -195,30 +398,209 @@ func (w WorkDir) AddTarget(target string, folderName string, dep KloneSource) er
 	})
 }
 
+// RemoveTarget removes the item named folderName from target, or the whole
+// target if folderName is empty. removeFn is called with the removed items
+// while the klone file is still locked, so that their files can be deleted
+// before anybody else reads the updated klone file.
+func (w WorkDir) RemoveTarget(target string, folderName string, removeFn func(target string, removed KloneFolder) error) error {
+	return w.editKloneFile(func(kf *kloneFile) error {
+		target = cleanRelativePath(target)
+
+		srcs, ok := kf.Targets[target]
+		if !ok {
+			return fmt.Errorf("target %q does not exist", target)
+		}
+
+		var kept, removed KloneFolder
+		for _, src := range srcs {
+			if folderName == "" || src.FolderName == cleanRelativePath(folderName) {
+				removed = append(removed, src)
+			} else {
+				kept = append(kept, src)
+			}
+		}
+
+		if len(removed) == 0 {
+			return fmt.Errorf("target %q has no folder %q", target, folderName)
+		}
+
+		if err := removeFn(target, removed); err != nil {
+			return err
+		}
+
+		if len(kept) == 0 {
+			delete(kf.Targets, target)
+		} else {
+			kf.Targets[target] = kept
+		}
+
+		return nil
+	})
+}
+
+// SetPatches replaces the patches of the item folderName of target. setFn
+// is called while the klone file is still locked, so that state that
+// depends on the patches can be updated before anybody else reads them.
+func (w WorkDir) SetPatches(target string, folderName string, patches []string, setFn func() error) error {
+	return w.editKloneFile(func(kf *kloneFile) error {
+		target = cleanRelativePath(target)
+		folderName = cleanRelativePath(folderName)
+
+		for i, src := range kf.Targets[target] {
+			if src.FolderName == folderName {
+				kf.Targets[target][i].Patches = patches
+				return setFn()
+			}
+		}
+
+		return fmt.Errorf("target %q has no folder %q", target, folderName)
+	})
+}
+
 func cleanRelativePath(src string) string {
 	return filepath.Join(".", filepath.Clean(filepath.Join("/", src)))
 }
 
+type FetchOptions struct {
+	// Selector limits the items that are passed to cleanFn and fetchFn.
+	Selector Selector
+	// DryRun discards all changes made by cleanFn instead of writing them
+	// to the klone and lock files.
+	DryRun bool
+	// Jobs is the maximum number of cleanFn calls that run concurrently.
+	// Values below 1 are treated as 1.
+	Jobs int
+}
+
+// ItemError is the error of a single item, returned by FetchTargets when
+// cleanFn fails for it.
+type ItemError struct {
+	Target     string
+	FolderName string
+	Err        error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("%s/%s: %v", e.Target, e.FolderName, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ItemErrors returns every ItemError in the tree of err, in order.
+func ItemErrors(err error) []*ItemError {
+	if err == nil {
+		return nil
+	}
+
+	if itemErr, ok := err.(*ItemError); ok {
+		return []*ItemError{itemErr}
+	}
+
+	var errs []error
+	switch err := err.(type) {
+	case interface{ Unwrap() []error }:
+		errs = err.Unwrap()
+	case interface{ Unwrap() error }:
+		errs = []error{err.Unwrap()}
+	}
+
+	var itemErrs []*ItemError
+	for _, err := range errs {
+		itemErrs = append(itemErrs, ItemErrors(err)...)
+	}
+	return itemErrs
+}
+
+// FetchTargets calls cleanFn for every item that matches the selector and
+// then fetchFn once per target that has at least one selected item, in a
+// stable order. fetchFn receives all items of the target, so that it knows
+// which folders must be kept, and a mask of the selected ones.
+//
+// Up to opts.Jobs cleanFn calls run concurrently. fetchFn is only called
+// once all cleanFn calls succeeded; otherwise an ItemError for every failed
+// item is returned, in the same stable order.
 func (w WorkDir) FetchTargets(
+	opts FetchOptions,
 	cleanFn func(string, string, *KloneSource) error,
-	fetchFn func(target string, srcs KloneFolder) error,
+	fetchFn func(target string, srcs KloneFolder, selected []bool) error,
 ) error {
-	return w.editKloneFile(func(kf *kloneFile) error {
-		for target, srcs := range kf.Targets {
-			for i, src := range srcs {
-				if err := cleanFn(target, src.FolderName, &src.KloneSource); err != nil {
-					return err
+	edit := w.editKloneFile
+	if opts.DryRun {
+		edit = w.viewKloneFile
+	}
+
+	selector := opts.Selector
+	return edit(func(kf *kloneFile) error {
+		type selectedItem struct {
+			target string
+			src    *KloneItem
+		}
+
+		var items []selectedItem
+		selected := make(map[string][]bool, len(kf.Targets))
+		for _, target := range slices.Sorted(maps.Keys(kf.Targets)) {
+			srcs := kf.Targets[target]
+
+			mask := make([]bool, len(srcs))
+			for i := range srcs {
+				if !selector.Matches(target, srcs[i]) {
+					continue
 				}
-				srcs[i] = src
+				mask[i] = true
+				items = append(items, selectedItem{target: target, src: &srcs[i]})
 			}
 
-			if err := fetchFn(target, srcs); err != nil {
-				return err
+			if slices.Contains(mask, true) {
+				selected[target] = mask
 			}
+		}
 
-			kf.Targets[target] = srcs
+		if len(items) == 0 && !selector.IsEmpty() {
+			return selector.NoMatchError()
+		}
+
+		errs := make([]error, len(items))
+		forEachConcurrently(len(items), opts.Jobs, func(i int) {
+			item := items[i]
+			if err := cleanFn(item.target, item.src.FolderName, &item.src.KloneSource); err != nil {
+				errs[i] = &ItemError{Target: item.target, FolderName: item.src.FolderName, Err: err}
+			}
+		})
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+
+		for _, target := range slices.Sorted(maps.Keys(selected)) {
+			if err := fetchFn(target, kf.Targets[target], selected[target]); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	})
 }
+
+// forEachConcurrently calls fn for every index below n, with at most jobs
+// calls running at the same time, and waits for all of them to return.
+func forEachConcurrently(n int, jobs int, fn func(i int)) {
+	jobs = max(1, min(jobs, n))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Go(func() {
+			for i := range indexes {
+				fn(i)
+			}
+		})
+	}
+
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mod
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rogpeppe/go-internal/lockedfile"
+	"gopkg.in/yaml.v3"
+)
+
+const lockFileName = "klone.lock"
+
+const lockFileHeader = `# This file is generated by klone and records the resolved upstream
+# revision and content hash of every item in klone.yaml. Do not edit it by
+# hand.
+`
+
+// lockFile stores the machine-managed state of a klone file. When a lock
+// file exists next to klone.yaml, resolved hashes are read from and written
+// to it instead of the repo_hash fields in klone.yaml.
+type lockFile struct {
+	Targets map[string][]LockItem `yaml:"targets"`
+}
+
+// LockItem records the revision and content an item was resolved to.
+// RepoURL, RepoRef and SHA256 are stored so that an entry is ignored once the
+// corresponding item in klone.yaml points at a different upstream.
+type LockItem struct {
+	FolderName string `yaml:"folder_name"`
+	// RepoURL is the location of the item, i.e. its local_path for local
+	// sources and its archive_url for archives.
+	RepoURL  string `yaml:"repo_url"`
+	RepoRef  string `yaml:"repo_ref"`
+	RepoHash string `yaml:"repo_hash,omitempty"`
+	RepoTag  string `yaml:"repo_tag,omitempty"`
+	// SHA256 is the pin of archives, which stays in klone.yaml.
+	SHA256      string `yaml:"sha256,omitempty"`
+	ContentHash string `yaml:"content_hash,omitempty"`
+}
+
+func (l *lockFile) find(target string, item KloneItem) (LockItem, bool) {
+	for _, entry := range l.Targets[target] {
+		if entry.FolderName == item.FolderName && entry.RepoURL == item.Location() && entry.RepoRef == item.RepoRef && entry.SHA256 == item.SHA256 {
+			return entry, true
+		}
+	}
+	return LockItem{}, false
+}
+
+// applyTo fills in the repo_hash, repo_tag and content_hash of every item
+// that does not pin a repo_hash or content_hash inline. An inline value takes
+// precedence, so that existing klone files and explicitly pinned items keep
+// working.
+func (l *lockFile) applyTo(kf *kloneFile) {
+	for target, srcs := range kf.Targets {
+		for i, src := range srcs {
+			if src.RepoHash != "" || src.ContentHash != "" {
+				continue
+			}
+			if entry, ok := l.find(target, src); ok {
+				srcs[i].RepoHash = entry.RepoHash
+				srcs[i].RepoTag = entry.RepoTag
+				srcs[i].ContentHash = entry.ContentHash
+			}
+		}
+	}
+}
+
+// moveFrom rebuilds the lock file from the hashes (and resolved tags) in kf
+// and removes them from kf, so that they are only written to the lock file.
+// Archives have no repo_hash, only their content_hash is moved.
+func (l *lockFile) moveFrom(kf *kloneFile) {
+	l.Targets = make(map[string][]LockItem, len(kf.Targets))
+	for target, srcs := range kf.Targets {
+		for i, src := range srcs {
+			if src.RepoHash == "" && src.ContentHash == "" {
+				continue
+			}
+
+			l.Targets[target] = append(l.Targets[target], LockItem{
+				FolderName:  src.FolderName,
+				RepoURL:     src.Location(),
+				RepoRef:     src.RepoRef,
+				RepoHash:    src.RepoHash,
+				RepoTag:     src.RepoTag,
+				SHA256:      src.SHA256,
+				ContentHash: src.ContentHash,
+			})
+			srcs[i].RepoHash = ""
+			srcs[i].RepoTag = ""
+			srcs[i].ContentHash = ""
+		}
+
+		slices.SortFunc(l.Targets[target], func(a, b LockItem) int {
+			return strings.Compare(a.FolderName, b.FolderName)
+		})
+	}
+}
+
+func (w WorkDir) lockFilePath() string {
+	return filepath.Join(string(w), lockFileName)
+}
+
+// HasLockFile reports whether the resolved hashes of this WorkDir are
+// stored in a lock file.
+func (w WorkDir) HasLockFile() (bool, error) {
+	_, err := os.Stat(w.lockFilePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// readLockFile reads the lock file, returning nil if there is none.
+func (w WorkDir) readLockFile() (*lockFile, error) {
+	data, err := lockedfile.Read(w.lockFilePath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock := lockFile{}
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	return &lock, nil
+}
+
+// editLockFile exclusively opens the lock file, if there is one, and calls
+// fn with its decoded contents (nil if there is no lock file). When fn
+// succeeds, the possibly modified contents are written back.
+func (w WorkDir) editLockFile(fn func(*lockFile) error) error {
+	exists, err := w.HasLockFile()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fn(nil)
+	}
+
+	file, err := lockedfile.Edit(w.lockFilePath())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lock := lockFile{}
+	if err := yaml.NewDecoder(file).Decode(&lock); err != nil && err != io.EOF {
+		return err
+	}
+
+	if err := fn(&lock); err != nil {
+		return err
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return err
+	}
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(lockFileHeader); err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+
+	return encoder.Encode(lock)
+}
+
+// MigrateToLockFile creates a lock file, if there is none yet, and moves
+// all repo_hash values from klone.yaml into it.
+func (w WorkDir) MigrateToLockFile() error {
+	file, err := lockedfile.OpenFile(w.lockFilePath(), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return w.editKloneFile(func(kf *kloneFile) error {
+		return nil
+	})
+}
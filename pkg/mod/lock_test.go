@@ -0,0 +1,240 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mod
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestMigrateToLockFile(t *testing.T) {
+	tempDirPath := t.TempDir()
+	workDir := WorkDir(tempDirPath)
+
+	initial := `# Test comment
+targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_hash: abc123
+      repo_path: path/to/repo1
+    - folder_name: Folder B
+      repo_url: https://github.com/repo2
+      repo_ref: main
+      repo_path: path/to/repo2
+`
+	if err := os.WriteFile(path.Join(tempDirPath, kloneFileName), []byte(initial), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+
+	if err := workDir.MigrateToLockFile(); err != nil {
+		t.Fatalf("MigrateToLockFile returned error: %v", err)
+	}
+
+	expectedKloneFile := `# Test comment
+targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_hash: ""
+      repo_path: path/to/repo1
+    - folder_name: Folder B
+      repo_url: https://github.com/repo2
+      repo_ref: main
+      repo_hash: ""
+      repo_path: path/to/repo2
+`
+	if got := readFile(t, path.Join(tempDirPath, kloneFileName)); got != expectedKloneFile {
+		t.Errorf("Expected klone file:\n%s\n\nBut got:\n%s", expectedKloneFile, got)
+	}
+
+	expectedLockFile := lockFileHeader + `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_hash: abc123
+`
+	if got := readFile(t, path.Join(tempDirPath, lockFileName)); got != expectedLockFile {
+		t.Errorf("Expected lock file:\n%s\n\nBut got:\n%s", expectedLockFile, got)
+	}
+
+	targets, err := workDir.ReadTargets()
+	if err != nil {
+		t.Fatalf("ReadTargets returned error: %v", err)
+	}
+	if hash := targets["target1"][0].RepoHash; hash != "abc123" {
+		t.Errorf("Expected hash from lock file to be abc123, but got %q", hash)
+	}
+}
+
+func TestLockFile_WritesOnlyLock(t *testing.T) {
+	tempDirPath := t.TempDir()
+	workDir := WorkDir(tempDirPath)
+
+	initial := `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_hash: ""
+      repo_path: path/to/repo1
+`
+	if err := os.WriteFile(path.Join(tempDirPath, kloneFileName), []byte(initial), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+	lock := lockFileHeader + `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_hash: abc123
+`
+	if err := os.WriteFile(path.Join(tempDirPath, lockFileName), []byte(lock), 0o644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	// Simulate an upgrade: the item sees the locked hash and bumps it.
+	if err := workDir.editKloneFile(func(kf *kloneFile) error {
+		src := kf.Targets["target1"][0]
+		if src.RepoHash != "abc123" {
+			t.Errorf("Expected locked hash abc123, but got %q", src.RepoHash)
+		}
+		kf.Targets["target1"][0].RepoHash = "def456"
+		return nil
+	}); err != nil {
+		t.Fatalf("editKloneFile returned error: %v", err)
+	}
+
+	if got := readFile(t, path.Join(tempDirPath, kloneFileName)); got != initial {
+		t.Errorf("Expected klone file to be unchanged, but got:\n%s", got)
+	}
+
+	expectedLockFile := lockFileHeader + `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_hash: def456
+`
+	if got := readFile(t, path.Join(tempDirPath, lockFileName)); got != expectedLockFile {
+		t.Errorf("Expected lock file:\n%s\n\nBut got:\n%s", expectedLockFile, got)
+	}
+
+	// Changing the ref in klone.yaml makes the locked hash stale.
+	changed := `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://github.com/repo1
+      repo_ref: v1.0.0
+      repo_path: path/to/repo1
+`
+	if err := os.WriteFile(path.Join(tempDirPath, kloneFileName), []byte(changed), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+
+	targets, err := workDir.ReadTargets()
+	if err != nil {
+		t.Fatalf("ReadTargets returned error: %v", err)
+	}
+	if hash := targets["target1"][0].RepoHash; hash != "" {
+		t.Errorf("Expected stale lock entry to be ignored, but got hash %q", hash)
+	}
+}
+
+func TestLockFile_ArchiveContentHash(t *testing.T) {
+	tempDirPath := t.TempDir()
+	workDir := WorkDir(tempDirPath)
+
+	initial := `targets:
+  target1:
+    - folder_name: Folder A
+      archive_url: https://example.com/a.tar.gz
+      sha256: aaaa
+      content_hash: h1:abc
+      repo_path: .
+`
+	if err := os.WriteFile(path.Join(tempDirPath, kloneFileName), []byte(initial), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+
+	if err := workDir.MigrateToLockFile(); err != nil {
+		t.Fatalf("MigrateToLockFile returned error: %v", err)
+	}
+
+	// archives keep their sha256 in klone.yaml, only the content_hash moves
+	expectedKloneFile := `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: ""
+      repo_ref: ""
+      repo_hash: ""
+      archive_url: https://example.com/a.tar.gz
+      sha256: aaaa
+      repo_path: .
+`
+	if got := readFile(t, path.Join(tempDirPath, kloneFileName)); got != expectedKloneFile {
+		t.Errorf("Expected klone file:\n%s\n\nBut got:\n%s", expectedKloneFile, got)
+	}
+
+	expectedLockFile := lockFileHeader + `targets:
+  target1:
+    - folder_name: Folder A
+      repo_url: https://example.com/a.tar.gz
+      repo_ref: ""
+      sha256: aaaa
+      content_hash: h1:abc
+`
+	if got := readFile(t, path.Join(tempDirPath, lockFileName)); got != expectedLockFile {
+		t.Errorf("Expected lock file:\n%s\n\nBut got:\n%s", expectedLockFile, got)
+	}
+
+	targets, err := workDir.ReadTargets()
+	if err != nil {
+		t.Fatalf("ReadTargets returned error: %v", err)
+	}
+	if hash := targets["target1"][0].ContentHash; hash != "h1:abc" {
+		t.Errorf("Expected content hash from lock file to be h1:abc, but got %q", hash)
+	}
+
+	// Changing the sha256 in klone.yaml makes the locked content hash stale.
+	changed := strings.Replace(expectedKloneFile, "sha256: aaaa", "sha256: bbbb", 1)
+	if err := os.WriteFile(path.Join(tempDirPath, kloneFileName), []byte(changed), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+
+	targets, err = workDir.ReadTargets()
+	if err != nil {
+		t.Fatalf("ReadTargets returned error: %v", err)
+	}
+	if hash := targets["target1"][0].ContentHash; hash != "" {
+		t.Errorf("Expected stale lock entry to be ignored, but got content hash %q", hash)
+	}
+}
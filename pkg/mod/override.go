@@ -0,0 +1,116 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const overrideFileName = "klone.override.yaml"
+
+// OverrideFileEnv names an override file to use instead of the
+// klone.override.yaml next to klone.yaml.
+const OverrideFileEnv = "KLONE_OVERRIDE_FILE"
+
+// Overrides replace the upstream of items with a local checkout, like
+// "replace" directives in go.mod. They are meant for local development and
+// are never written to klone.yaml or the lock file.
+type Overrides struct {
+	// Path is the override file the overrides were read from.
+	Path string
+	// Replace maps a repo_url, or a target/folder_name, to a local directory
+	// that is used as the root of the repository. Relative directories are
+	// resolved against the directory of the override file.
+	Replace map[string]string `yaml:"replace"`
+}
+
+// Lookup returns the local source that replaces the upstream of item in
+// target. It keeps the repo_path and filters of item, so that the local
+// directory is used like a checkout of the repository. A target/folder_name
+// entry takes precedence over a repo_url entry.
+func (o *Overrides) Lookup(target string, item KloneItem) (KloneSource, bool) {
+	if o == nil {
+		return KloneSource{}, false
+	}
+
+	dir, ok := o.Replace[filepath.ToSlash(filepath.Join(target, item.FolderName))]
+	if !ok {
+		dir, ok = o.Replace[item.Location()]
+	}
+	if !ok {
+		return KloneSource{}, false
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(o.Path), dir)
+	}
+
+	return KloneSource{
+		LocalPath: filepath.Clean(dir),
+		RepoPath:  item.RepoPath,
+		Include:   item.Include,
+		Exclude:   item.Exclude,
+	}, true
+}
+
+// ReadOverrides reads the override file named by KLONE_OVERRIDE_FILE, or
+// else the klone.override.yaml next to klone.yaml. It returns nil if there
+// is no override file.
+func (w WorkDir) ReadOverrides() (*Overrides, error) {
+	path := os.Getenv(OverrideFileEnv)
+	explicit := path != ""
+	if !explicit {
+		path = filepath.Join(string(w), overrideFileName)
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := Overrides{}
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	overrides.Path = path
+
+	for key, dir := range overrides.Replace {
+		if key == "" || dir == "" {
+			return nil, fmt.Errorf("invalid replace entry %q: %q in %s: both must be set", key, dir, path)
+		}
+	}
+
+	if len(overrides.Replace) == 0 {
+		return nil, nil
+	}
+
+	return &overrides, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mod
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/config"
+)
+
+// Selector selects a subset of the items of a klone file. An item is
+// selected if it matches at least one value of every non-empty field, so an
+// empty Selector selects every item.
+type Selector struct {
+	// Paths selects the items whose destination (target/folder_name) is
+	// equal to or inside one of the paths.
+	Paths []string
+	// Targets selects the items of the listed targets.
+	Targets []string
+	// Folders selects the items with one of the listed folder names.
+	Folders []string
+	// RepoURLs selects the items that are kloned from one of the listed
+	// repositories or archives.
+	RepoURLs []string
+}
+
+func (s Selector) IsEmpty() bool {
+	return len(s.Paths) == 0 && len(s.Targets) == 0 && len(s.Folders) == 0 && len(s.RepoURLs) == 0
+}
+
+// Matches reports whether the item of target is selected. target and the
+// item's folder name are expected to be canonicalized.
+func (s Selector) Matches(target string, item KloneItem) bool {
+	if len(s.Paths) > 0 {
+		dest := filepath.Join(target, item.FolderName)
+		if !slices.ContainsFunc(s.Paths, func(p string) bool {
+			p = cleanRelativePath(p)
+			return p == "." || p == dest || strings.HasPrefix(dest, p+string(filepath.Separator))
+		}) {
+			return false
+		}
+	}
+
+	if len(s.Targets) > 0 && !slices.ContainsFunc(s.Targets, func(t string) bool {
+		return cleanRelativePath(t) == target
+	}) {
+		return false
+	}
+
+	if len(s.Folders) > 0 && !slices.ContainsFunc(s.Folders, func(f string) bool {
+		return cleanRelativePath(f) == item.FolderName
+	}) {
+		return false
+	}
+
+	if len(s.RepoURLs) > 0 && !slices.Contains(s.RepoURLs, item.Location()) {
+		return false
+	}
+
+	return true
+}
+
+// NoMatchError returns the error of a command whose selector matches none
+// of the items.
+func (s Selector) NoMatchError() error {
+	return fmt.Errorf("no items match the selection %s", s)
+}
+
+func (s Selector) String() string {
+	repoURLs := make([]string, len(s.RepoURLs))
+	for i, repoURL := range s.RepoURLs {
+		repoURLs[i] = config.RedactURL(repoURL)
+	}
+
+	var parts []string
+	for _, field := range []struct {
+		name   string
+		values []string
+	}{
+		{"paths", s.Paths},
+		{"targets", s.Targets},
+		{"folders", s.Folders},
+		{"repo_urls", repoURLs},
+	} {
+		if len(field.values) > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%s", field.name, strings.Join(field.values, ",")))
+		}
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mod
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	item := KloneItem{
+		FolderName: "go",
+		KloneSource: KloneSource{
+			RepoURL: "https://github.com/cert-manager/makefile-modules.git",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		selector Selector
+		want     bool
+	}{
+		{name: "empty", selector: Selector{}, want: true},
+		{name: "exact path", selector: Selector{Paths: []string{"make/_shared/go"}}, want: true},
+		{name: "target path", selector: Selector{Paths: []string{"make/_shared"}}, want: true},
+		{name: "parent path", selector: Selector{Paths: []string{"./make/"}}, want: true},
+		{name: "partial segment", selector: Selector{Paths: []string{"make/_sha"}}, want: false},
+		{name: "other path", selector: Selector{Paths: []string{"make/_shared/tools"}}, want: false},
+		{name: "any of paths", selector: Selector{Paths: []string{"other", "make/_shared/go"}}, want: true},
+		{name: "target", selector: Selector{Targets: []string{"make/_shared"}}, want: true},
+		{name: "other target", selector: Selector{Targets: []string{"make"}}, want: false},
+		{name: "folder", selector: Selector{Folders: []string{"go"}}, want: true},
+		{name: "other folder", selector: Selector{Folders: []string{"tools"}}, want: false},
+		{name: "repo url", selector: Selector{RepoURLs: []string{"https://github.com/cert-manager/makefile-modules.git"}}, want: true},
+		{name: "other repo url", selector: Selector{RepoURLs: []string{"https://github.com/cert-manager/community.git"}}, want: false},
+		{name: "all fields must match", selector: Selector{Targets: []string{"make/_shared"}, Folders: []string{"tools"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Matches("make/_shared", item); got != tt.want {
+				t.Errorf("%v.Matches() = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchTargets_Selector(t *testing.T) {
+	tempDirPath := t.TempDir()
+	initial := `targets:
+  a:
+    - folder_name: x
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_path: path
+    - folder_name: "y"
+      repo_url: https://github.com/repo2
+      repo_ref: main
+      repo_path: path
+  b:
+    - folder_name: z
+      repo_url: https://github.com/repo1
+      repo_ref: main
+      repo_path: path
+`
+	if err := os.WriteFile(path.Join(tempDirPath, kloneFileName), []byte(initial), 0o644); err != nil {
+		t.Fatalf("Failed to write klone file: %v", err)
+	}
+
+	var cleaned, fetched []string
+	err := WorkDir(tempDirPath).FetchTargets(
+		FetchOptions{Selector: Selector{Targets: []string{"a"}, RepoURLs: []string{"https://github.com/repo2"}}},
+		func(target string, folderName string, src *KloneSource) error {
+			cleaned = append(cleaned, target+"/"+folderName)
+			src.RepoHash = "abc123"
+			return nil
+		},
+		func(target string, srcs KloneFolder, selected []bool) error {
+			for i, src := range srcs {
+				if selected[i] {
+					fetched = append(fetched, target+"/"+src.FolderName)
+				}
+			}
+			if len(srcs) != 2 {
+				t.Errorf("Expected fetchFn to receive all 2 items of target %s, but got %d", target, len(srcs))
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("FetchTargets returned error: %v", err)
+	}
+
+	want := []string{"a/y"}
+	if !slices.Equal(cleaned, want) || !slices.Equal(fetched, want) {
+		t.Errorf("Expected only %v to be cleaned and fetched, but got %v and %v", want, cleaned, fetched)
+	}
+
+	targets, err := WorkDir(tempDirPath).ReadTargets()
+	if err != nil {
+		t.Fatalf("ReadTargets returned error: %v", err)
+	}
+	if targets["a"][0].RepoHash != "" || targets["a"][1].RepoHash != "abc123" || targets["b"][0].RepoHash != "" {
+		t.Errorf("Expected only the selected item to be pinned, but got %+v", targets)
+	}
+
+	err = WorkDir(tempDirPath).FetchTargets(
+		FetchOptions{Selector: Selector{Folders: []string{"missing"}}},
+		func(string, string, *KloneSource) error { return nil },
+		func(string, KloneFolder, []bool) error { return nil },
+	)
+	if err == nil {
+		t.Errorf("Expected an error for a selector that matches nothing, but got nil")
+	}
+}
+
+func TestSelectorString(t *testing.T) {
+	s := Selector{
+		Folders:  []string{"x", "y"},
+		RepoURLs: []string{"https://token@github.com/org/repo", "https://github.com/org/other"},
+	}
+
+	want := "(folders=x,y repo_urls=https://xxxxx@github.com/org/repo,https://github.com/org/other)"
+	if got := s.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/download"
+	"github.com/cert-manager/klone/pkg/download/git"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// DiffMode selects the two versions of an item that DiffFolder compares.
+type DiffMode string
+
+const (
+	// DiffLocal compares the pinned content, with its patches applied, with
+	// the destination folder.
+	DiffLocal DiffMode = "local"
+	// DiffUpstream compares the pinned content with the content that
+	// repo_ref currently refers to, i.e. what "klone upgrade" would pin.
+	// Patches are not applied to either side.
+	DiffUpstream DiffMode = "upstream"
+)
+
+// ItemDiff describes an item that DiffFolder compared. For DiffLocal, NewHash
+// and NewTag equal the pinned version.
+type ItemDiff struct {
+	Target     string
+	FolderName string
+	OldHash    string
+	NewHash    string
+	NewTag     string
+}
+
+// DiffFolder writes a unified diff of the selected items to w, with the
+// paths of both sides prefixed by "a/" and "b/" followed by the
+// target/folder_name of the item. It returns the items that differ.
+// Neither the destination folders nor the klone file are modified.
+func DiffFolder(ctx context.Context, workDirPath string, selector mod.Selector, mode DiffMode, w io.Writer) ([]ItemDiff, error) {
+	workDirPath, err := resolveWorkDir(workDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = withConfig(ctx, mod.WorkDir(workDirPath))
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != DiffLocal && mode != DiffUpstream {
+		return nil, fmt.Errorf("unknown diff mode %q, must be %q or %q", mode, DiffLocal, DiffUpstream)
+	}
+
+	targets, err := mod.WorkDir(workDirPath).ReadTargets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	diffDir, err := os.MkdirTemp("", "klone-diff-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(diffDir)
+
+	for _, side := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(diffDir, side), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	var items []ItemDiff
+	matched := false
+	if err := forEachItem(workDirPath, targets, func(target string, folderPath string, src mod.KloneItem) error {
+		if !selector.Matches(target, src) {
+			return nil
+		}
+		matched = true
+
+		if src.Pin() == "" {
+			return fmt.Errorf("%s/%s is not pinned, run \"klone sync\" to pin it", target, src.FolderName)
+		}
+
+		item := ItemDiff{
+			Target:     target,
+			FolderName: src.FolderName,
+			OldHash:    src.Pin(),
+			NewHash:    src.Pin(),
+			NewTag:     src.RepoTag,
+		}
+
+		rel := filepath.Join(target, src.FolderName)
+		oldPath := filepath.Join(diffDir, "a", rel)
+		newPath := filepath.Join(diffDir, "b", rel)
+
+		var changed bool
+		switch mode {
+		case DiffLocal:
+			changed, err = stageLocalDiff(ctx, workDirPath, target, src, folderPath, oldPath, newPath)
+		case DiffUpstream:
+			changed, err = stageUpstreamDiff(ctx, src.KloneSource, &item, oldPath, newPath)
+		}
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			items = append(items, item)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if !matched && !selector.IsEmpty() {
+		return nil, selector.NoMatchError()
+	}
+
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	if _, err := git.Diff(ctx, diffDir, "a", "b", w); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// stageLocalDiff copies the pinned content of src, with its patches
+// applied, to oldPath and the destination folder to newPath, and reports
+// whether they differ.
+func stageLocalDiff(ctx context.Context, workDirPath string, target string, src mod.KloneItem, folderPath string, oldPath string, newPath string) (bool, error) {
+	cachePath, err := cache.FetchToCache(ctx, src.KloneSource, download.Get)
+	if err != nil {
+		return false, err
+	}
+
+	contentPath, cleanup, err := materialise(ctx, workDirPath, target, src, cachePath)
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	diffs, err := cache.CompareTrees(contentPath, folderPath)
+	if err != nil {
+		return false, err
+	}
+	if len(diffs) == 0 {
+		return false, nil
+	}
+
+	if _, err := cache.SyncTree(ctx, contentPath, oldPath); err != nil {
+		return false, err
+	}
+
+	// a missing destination folder is shown as all files being deleted
+	if _, err := os.Stat(folderPath); os.IsNotExist(err) {
+		return true, os.MkdirAll(newPath, 0o755)
+	}
+	_, err = cache.SyncTree(ctx, folderPath, newPath)
+	return true, err
+}
+
+// stageUpstreamDiff copies the pinned content of src to oldPath and the
+// content its repo_ref currently refers to to newPath, records the new
+// version in item and reports whether the content differs.
+func stageUpstreamDiff(ctx context.Context, src mod.KloneSource, item *ItemDiff, oldPath string, newPath string) (bool, error) {
+	// archives are pinned by their sha256 and have no newer version
+	if src.Kind() == mod.SourceArchive {
+		return false, nil
+	}
+
+	latest, err := download.ResolveRef(ctx, src)
+	if err != nil {
+		return false, err
+	}
+	if latest.Hash == src.RepoHash {
+		return false, nil
+	}
+
+	oldCachePath, err := cache.FetchToCache(ctx, src, download.Get)
+	if err != nil {
+		return false, err
+	}
+
+	latestSrc := src
+	latestSrc.RepoHash = latest.Hash
+	latestSrc.RepoTag = latest.Tag
+	latestSrc.ContentHash = ""
+
+	newCachePath, err := cache.FetchToCache(ctx, latestSrc, download.Get)
+	if err != nil {
+		return false, err
+	}
+
+	item.NewHash = latest.Hash
+	item.NewTag = latest.Tag
+
+	diffs, err := cache.CompareTrees(oldCachePath, newCachePath)
+	if err != nil || len(diffs) == 0 {
+		// the new version does not change the kloned files, but it is still
+		// reported as it would be pinned by an upgrade
+		return err == nil, err
+	}
+
+	if _, err := cache.SyncTree(ctx, oldCachePath, oldPath); err != nil {
+		return false, err
+	}
+	_, err = cache.SyncTree(ctx, newCachePath, newPath)
+	return true, err
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestDiffFolder(t *testing.T) {
+	repo, git := testutil.NewGitRepo(t)
+	testutil.WriteFiles(t, repo, map[string]string{
+		"modules/go/01_mod.mk": "line 1\nline 2\n",
+		"modules/go/README.md": "readme\n",
+	})
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	hash := git("rev-parse", "HEAD")
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	diff := func(mode DiffMode) ([]ItemDiff, string) {
+		t.Helper()
+		out := &bytes.Buffer{}
+		items, err := DiffFolder(t.Context(), workDir, mod.Selector{}, mode, out)
+		if err != nil {
+			t.Fatalf("DiffFolder(%s): %v", mode, err)
+		}
+		return items, out.String()
+	}
+
+	for _, mode := range []DiffMode{DiffLocal, DiffUpstream} {
+		if items, out := diff(mode); len(items) != 0 || out != "" {
+			t.Errorf("Expected no %s differences, but got %+v:\n%s", mode, items, out)
+		}
+	}
+
+	// local modifications
+	testutil.WriteFiles(t, workDir, map[string]string{"make/_shared/go/01_mod.mk": "line 1\nlocal\n"})
+
+	items, out := diff(DiffLocal)
+	if len(items) != 1 || items[0].OldHash != hash {
+		t.Errorf("Expected one local difference, but got %+v", items)
+	}
+	for _, want := range []string{"a/make/_shared/go/01_mod.mk", "b/make/_shared/go/01_mod.mk", "-line 2", "+local"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the local diff to contain %q, but got:\n%s", want, out)
+		}
+	}
+
+	// a new upstream commit
+	testutil.WriteFiles(t, repo, map[string]string{"modules/go/02_new.mk": "new\n", "other/file": "other\n"})
+	git("add", "-A")
+	git("commit", "-q", "-m", "update")
+	newHash := git("rev-parse", "HEAD")
+
+	items, out = diff(DiffUpstream)
+	if len(items) != 1 || items[0].OldHash != hash || items[0].NewHash != newHash {
+		t.Errorf("Expected one upstream difference from %s to %s, but got %+v", hash, newHash, items)
+	}
+	if !strings.Contains(out, "b/make/_shared/go/02_new.mk") || !strings.Contains(out, "+new") {
+		t.Errorf("Expected the upstream diff to add 02_new.mk, but got:\n%s", out)
+	}
+	if strings.Contains(out, "other/file") || strings.Contains(out, "local") {
+		t.Errorf("Expected the upstream diff to only contain repo_path changes, but got:\n%s", out)
+	}
+
+	// a selector that matches no item is an error, not "no differences"
+	selector := mod.Selector{Paths: []string{"make/_shared/missing"}}
+	if _, err := DiffFolder(t.Context(), workDir, selector, DiffLocal, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "no items match the selection") {
+		t.Errorf("Expected an error for a selector without matches, but got %v", err)
+	}
+
+	// klone.yaml is not modified
+	targets, err := mod.WorkDir(workDir).ReadTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := targets["make/_shared"][0].RepoHash; got != hash {
+		t.Errorf("Expected repo_hash to stay %s, but got %s", hash, got)
+	}
+}
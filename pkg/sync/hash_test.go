@@ -0,0 +1,151 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestSyncFolder_ContentHash(t *testing.T) {
+	repo, _ := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+	})
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	targets, err := mod.WorkDir(workDir).ReadTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := targets["make/_shared"][0].KloneSource
+	if !strings.HasPrefix(src.ContentHash, "h1:") {
+		t.Fatalf("Expected sync to pin a content hash, but got %q", src.ContentHash)
+	}
+
+	// tamper with the cache entry
+	cachePath, exists, err := cache.Lookup(t.Context(), src)
+	if err != nil || !exists {
+		t.Fatalf("Lookup: %v, %v", exists, err)
+	}
+	if err := os.WriteFile(filepath.Join(cachePath, "01_mod.mk"), []byte("tampered\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); !errors.Is(err, cache.ErrContentHashMismatch) {
+		t.Fatalf("Expected SyncFolder to fail with ErrContentHashMismatch, but got %v", err)
+	}
+	if _, err := VerifyFolder(t.Context(), workDir); !errors.Is(err, cache.ErrContentHashMismatch) {
+		t.Errorf("Expected VerifyFolder to fail with ErrContentHashMismatch, but got %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(workDir, "make/_shared/go/01_mod.mk"))
+	if err != nil || string(got) != "upstream\n" {
+		t.Errorf("Expected the destination to be untouched, but got %q, %v", got, err)
+	}
+}
+
+func TestSyncFolder_ArchiveContentHash(t *testing.T) {
+	archives := map[string][]byte{
+		"/v1.tar.gz": testutil.TarGz(t, []testutil.Entry{{Name: "config/a.yaml", Content: "v1\n"}}),
+		"/v2.tar.gz": testutil.TarGz(t, []testutil.Entry{{Name: "config/a.yaml", Content: "v2\n"}}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	writeArchive := func(name string) {
+		t.Helper()
+
+		targets, err := mod.WorkDir(workDir).ReadTargets()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			t.Fatal(err)
+		}
+		contentHash := ""
+		if len(targets["vendor"]) > 0 {
+			contentHash = targets["vendor"][0].ContentHash
+		}
+
+		sum := sha256.Sum256(archives["/"+name])
+		testutil.WriteFiles(t, workDir, map[string]string{
+			"klone.yaml": `targets:
+  vendor:
+    - folder_name: config
+      archive_url: ` + server.URL + "/" + name + `
+      sha256: ` + hex.EncodeToString(sum[:]) + `
+      content_hash: "` + contentHash + `"
+      repo_path: config
+`,
+		})
+	}
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	writeArchive("v1.tar.gz")
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	// bumping the sha256 keeps the content hash of the old archive, which
+	// must be re-pinned by an upgrade
+	writeArchive("v2.tar.gz")
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); !errors.Is(err, cache.ErrContentHashMismatch) {
+		t.Fatalf("Expected SyncFolder to fail with ErrContentHashMismatch, but got %v", err)
+	}
+	if _, err := SyncFolder(t.Context(), workDir, Options{ForceUpgrade: true}); err != nil {
+		t.Fatalf("SyncFolder with ForceUpgrade: %v", err)
+	}
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder after upgrade: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(workDir, "vendor/config/a.yaml"))
+	if err != nil || string(got) != "v2\n" {
+		t.Errorf("Expected the upgraded archive content, but got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/download/local"
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+func TestSyncFolder_LocalPath(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  vendor:
+    - folder_name: config
+      local_path: shared
+      repo_path: config
+`,
+		"shared/config/a.yaml": "a\n",
+		"shared/other.yaml":    "other\n",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	readSource := func() mod.KloneSource {
+		t.Helper()
+		targets, err := mod.WorkDir(workDir).ReadTargets()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return targets["vendor"][0].KloneSource
+	}
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	pinned := readSource().RepoHash
+	if !strings.HasPrefix(pinned, "h1:") {
+		t.Fatalf("Expected sync to pin a content hash, but got %q", pinned)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "vendor/config/a.yaml")); got != "a\n" {
+		t.Errorf("a.yaml = %q, want %q", got, "a\n")
+	}
+
+	// files outside of repo_path do not change the version
+	testutil.WriteFiles(t, workDir, map[string]string{"shared/other.yaml": "changed\n"})
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	testutil.WriteFiles(t, workDir, map[string]string{"shared/config/a.yaml": "changed\n"})
+
+	var changed local.ChangedError
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); !errors.As(err, &changed) {
+		t.Fatalf("Expected SyncFolder to fail with a ChangedError, but got %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "vendor/config/a.yaml")); got != "a\n" {
+		t.Errorf("Expected the destination to be untouched, but got %q", got)
+	}
+
+	report, err := SyncFolder(t.Context(), workDir, Options{ForceUpgrade: true})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if len(report.Items) != 1 || report.Items[0].OldHash != pinned || report.Items[0].NewHash == pinned {
+		t.Errorf("Expected the upgrade to re-pin %s, but got %+v", pinned, report.Items)
+	}
+	if got := readSource().RepoHash; got != report.Items[0].NewHash {
+		t.Errorf("Expected repo_hash %s to be written, but got %s", report.Items[0].NewHash, got)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "vendor/config/a.yaml")); got != "changed\n" {
+		t.Errorf("a.yaml = %q, want %q", got, "changed\n")
+	}
+
+	if _, err := os.Stat(filepath.Join(workDir, "vendor/config/other.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected files outside of repo_path not to be kloned, but got %v", err)
+	}
+}
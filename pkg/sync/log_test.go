@@ -0,0 +1,82 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestSyncFolder_CommitLog(t *testing.T) {
+	repo, git := testutil.NewGitRepo(t)
+	testutil.WriteFiles(t, repo, map[string]string{
+		"modules/go/01_mod.mk": "1\n",
+	})
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	hash := git("rev-parse", "HEAD")
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_hash: ` + hash + `
+      repo_path: modules/go
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	for _, change := range []struct{ file, subject string }{
+		{"modules/go/01_mod.mk", "Update go module"},
+		{"other/file", "Unrelated change"},
+	} {
+		testutil.WriteFiles(t, repo, map[string]string{change.file: change.subject + "\n"})
+		git("add", "-A")
+		git("commit", "-q", "-m", change.subject)
+	}
+
+	report, err := SyncFolder(t.Context(), workDir, Options{ForceUpgrade: true, CommitLog: true})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	if len(report.Items) != 1 {
+		t.Fatalf("Expected one item, but got %+v", report.Items)
+	}
+	var subjects []string
+	for _, commit := range report.Items[0].Commits {
+		subjects = append(subjects, commit.Subject)
+	}
+	if got := strings.Join(subjects, ","); got != "Update go module" {
+		t.Errorf("Expected only the commit touching repo_path, but got %q", got)
+	}
+
+	// nothing to list once the item is up to date
+	report, err = SyncFolder(t.Context(), workDir, Options{ForceUpgrade: true, CommitLog: true})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if report.Items[0].Commits != nil {
+		t.Errorf("Expected no commits for an unchanged hash, but got %+v", report.Items[0].Commits)
+	}
+}
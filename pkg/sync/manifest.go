@@ -0,0 +1,199 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cert-manager/klone/pkg/cache"
+)
+
+// ErrLocalModifications is returned when a sync would overwrite or delete
+// files that were modified since klone last wrote them.
+var ErrLocalModifications = errors.New("local modifications would be lost")
+
+const (
+	stateDirName     = ".klone"
+	manifestFileName = "manifest.json"
+)
+
+// manifest records the sha256 of every file that sync last wrote to each
+// item, keyed by the slash separated target/folder_name of the item. It is
+// local state of a checkout and is ignored by git.
+type manifest struct {
+	Items map[string]manifestItem `json:"items"`
+}
+
+type manifestItem struct {
+	Files map[string]string `json:"files"`
+}
+
+func manifestPath(workDirPath string) string {
+	return filepath.Join(workDirPath, stateDirName, manifestFileName)
+}
+
+func manifestKey(target string, folderName string) string {
+	return filepath.ToSlash(filepath.Join(target, folderName))
+}
+
+// readManifest reads the manifest of workDirPath, returning an empty one if
+// sync did not write one yet.
+func readManifest(workDirPath string) (*manifest, error) {
+	m := &manifest{Items: map[string]manifestItem{}}
+
+	data, err := os.ReadFile(manifestPath(workDirPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s, remove it to continue: %w", manifestPath(workDirPath), err)
+	}
+	if m.Items == nil {
+		m.Items = map[string]manifestItem{}
+	}
+
+	return m, nil
+}
+
+// write atomically replaces the manifest of workDirPath. The state folder
+// is created with a .gitignore, so that the manifest is never committed.
+func (m *manifest) write(workDirPath string) error {
+	stateDir := filepath.Join(workDirPath, stateDirName)
+	if err := cache.AssertNoSymlinkInSubpath(workDirPath, stateDirName); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+
+	gitignore := filepath.Join(stateDir, ".gitignore")
+	if _, err := os.Lstat(gitignore); errors.Is(err, fs.ErrNotExist) {
+		if err := os.WriteFile(gitignore, []byte("*\n"), 0o644); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(stateDir, manifestFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), manifestPath(workDirPath))
+}
+
+// record stores the files that are now in destPath as written by sync.
+func (m *manifest) record(key string, destPath string) error {
+	files, err := cache.HashFiles(destPath)
+	if err != nil {
+		return err
+	}
+
+	m.Items[key] = manifestItem{Files: files}
+	return nil
+}
+
+// forget removes the items at or below the slash separated path rel.
+func (m *manifest) forget(rel string) {
+	for key := range m.Items {
+		if key == rel || strings.HasPrefix(key, rel+"/") {
+			delete(m.Items, key)
+		}
+	}
+}
+
+// localModifications returns the files in destPath that were modified or
+// added since sync last wrote the item key, sorted by path. Deleted files
+// are not listed, as syncing only restores them. Items that sync never
+// wrote have no local modifications.
+func (m *manifest) localModifications(key string, destPath string) ([]string, error) {
+	recorded, ok := m.Items[key]
+	if !ok {
+		return nil, nil
+	}
+
+	current, err := cache.HashFiles(destPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var modified []string
+	for _, name := range slices.Sorted(maps.Keys(current)) {
+		if recorded.Files[name] != current[name] {
+			modified = append(modified, name)
+		}
+	}
+
+	return modified, nil
+}
+
+func localModificationsError(key string, modified []string) error {
+	return fmt.Errorf("%w in %s, run with --force to discard them or save them with \"klone patch\":\n  %s",
+		ErrLocalModifications, key, strings.Join(modified, "\n  "))
+}
+
+// checkDeletedFolder fails if deleting the folder folderPath would discard
+// local modifications of an item at or below it.
+func (m *manifest) checkDeletedFolder(workDirPath string, folderPath string) error {
+	rel, err := filepath.Rel(workDirPath, folderPath)
+	if err != nil {
+		return err
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, key := range slices.Sorted(maps.Keys(m.Items)) {
+		if key != rel && !strings.HasPrefix(key, rel+"/") {
+			continue
+		}
+
+		modified, err := m.localModifications(key, filepath.Join(workDirPath, filepath.FromSlash(key)))
+		if err != nil {
+			return err
+		}
+		if len(modified) > 0 {
+			return fmt.Errorf("%w in %s, run with --force to delete it anyway:\n  %s",
+				ErrLocalModifications, key, strings.Join(modified, "\n  "))
+		}
+	}
+
+	return nil
+}
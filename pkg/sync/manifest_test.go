@@ -0,0 +1,183 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestSyncFolder_LocalModifications(t *testing.T) {
+	repo, _ := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+		"modules/go/02_mod.mk": "upstream\n",
+	})
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, ".klone/.gitignore")); got != "*\n" {
+		t.Errorf("Expected the state folder to be ignored by git, but got %q", got)
+	}
+
+	// deleted files are restored without complaint
+	if err := os.Remove(filepath.Join(workDir, "make/_shared/go/02_mod.mk")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"make/_shared/go/01_mod.mk": "local tweak\n",
+		"make/_shared/go/03_new.mk": "new\n",
+	})
+
+	_, err := SyncFolder(t.Context(), workDir, Options{})
+	if !errors.Is(err, ErrLocalModifications) {
+		t.Fatalf("Expected SyncFolder to fail with ErrLocalModifications, but got %v", err)
+	}
+	for _, want := range []string{"01_mod.mk", "03_new.mk"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to list %s, but got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "02_mod.mk") {
+		t.Errorf("Expected the error to only list modified files, but got %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "local tweak\n" {
+		t.Errorf("Expected the local modification to be kept, but got %q", got)
+	}
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{Force: true}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "upstream\n" {
+		t.Errorf("Expected --force to restore the upstream, but got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "make/_shared/go/03_new.mk")); !os.IsNotExist(err) {
+		t.Errorf("Expected --force to remove the added file, but got %v", err)
+	}
+
+	// after a forced sync, the folder is clean again
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+}
+
+func TestSyncFolder_LocalModificationsOfRemovedItems(t *testing.T) {
+	repo, _ := testutil.NewGitRepoWithFiles(t, map[string]string{"modules/go/01_mod.mk": "upstream\n"})
+
+	workDir := t.TempDir()
+	klone := func(folders ...string) string {
+		content := "targets:\n  make/_shared:\n"
+		for _, folder := range folders {
+			content += "    - folder_name: " + folder + "\n      repo_url: " + repo + "\n      repo_ref: main\n      repo_path: modules/go\n"
+		}
+		return content
+	}
+	testutil.WriteFiles(t, workDir, map[string]string{"klone.yaml": klone("go", "tools")})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	// tools is removed from klone.yaml by hand after it was modified
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml":                   klone("go"),
+		"make/_shared/tools/01_mod.mk": "local tweak\n",
+	})
+
+	_, err := SyncFolder(t.Context(), workDir, Options{})
+	if !errors.Is(err, ErrLocalModifications) || !strings.Contains(err.Error(), "make/_shared/tools") {
+		t.Fatalf("Expected SyncFolder to refuse to delete make/_shared/tools, but got %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/tools/01_mod.mk")); got != "local tweak\n" {
+		t.Errorf("Expected the local modification to be kept, but got %q", got)
+	}
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{Force: true}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "make/_shared/tools")); !os.IsNotExist(err) {
+		t.Errorf("Expected --force to delete the removed folder, but got %v", err)
+	}
+	written, err := readManifest(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := written.Items["make/_shared/tools"]; ok {
+		t.Errorf("Expected the removed item to be forgotten, but got %v", written.Items)
+	}
+}
+
+func TestRemoveFolder_LocalModifications(t *testing.T) {
+	repo, _ := testutil.NewGitRepoWithFiles(t, map[string]string{"modules/go/01_mod.mk": "upstream\n"})
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	testutil.WriteFiles(t, workDir, map[string]string{"make/_shared/go/01_mod.mk": "local tweak\n"})
+
+	err := RemoveFolder(workDir, "make/_shared", "go", false)
+	if !errors.Is(err, ErrLocalModifications) || !strings.Contains(err.Error(), "01_mod.mk") {
+		t.Fatalf("Expected RemoveFolder to fail with ErrLocalModifications, but got %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "local tweak\n" {
+		t.Errorf("Expected the local modification to be kept, but got %q", got)
+	}
+	if !strings.Contains(readFileOrFail(t, filepath.Join(workDir, "klone.yaml")), "folder_name: go") {
+		t.Errorf("Expected the item to stay in klone.yaml")
+	}
+
+	if err := RemoveFolder(workDir, "make/_shared", "go", true); err != nil {
+		t.Fatalf("RemoveFolder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "make/_shared/go")); !os.IsNotExist(err) {
+		t.Errorf("Expected --force to delete the folder, but got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestSyncFolder_Override(t *testing.T) {
+	repo, hash := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+	})
+
+	checkout := t.TempDir()
+	testutil.WriteFiles(t, checkout, map[string]string{
+		"modules/go/01_mod.mk": "local change\n",
+	})
+
+	workDir := t.TempDir()
+	kloneFile := `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_hash: ` + hash + `
+      repo_path: modules/go
+`
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml":          kloneFile,
+		"klone.override.yaml": "replace:\n  " + repo + ": " + checkout + "\n",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	report, err := SyncFolder(t.Context(), workDir, Options{})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "local change\n" {
+		t.Errorf("Expected the override to be kloned, but got %q", got)
+	}
+	if len(report.Items) != 1 || report.Items[0].Override != checkout || report.Items[0].NewHash != hash {
+		t.Errorf("Expected the report to list the override and keep the pinned hash, but got %+v", report.Items)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "klone.yaml")); got != kloneFile {
+		t.Errorf("Expected klone.yaml to be untouched, but got:\n%s", got)
+	}
+
+	// a target/folder_name entry takes precedence and is relative to the
+	// override file
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"other/modules/go/01_mod.mk": "other checkout\n",
+		"klone.override.yaml":        "replace:\n  " + repo + ": " + checkout + "\n  make/_shared/go: other\n",
+	})
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "other checkout\n" {
+		t.Errorf("Expected the target/folder_name override to be kloned, but got %q", got)
+	}
+
+	// without overrides, the pinned upstream is restored
+	if err := os.Remove(filepath.Join(workDir, "klone.override.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "upstream\n" {
+		t.Errorf("Expected the upstream to be restored, but got %q", got)
+	}
+}
+
+func TestSyncFolder_OverrideFileEnv(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{"klone.yaml": "targets: {}\n"})
+	t.Setenv("KLONE_OVERRIDE_FILE", filepath.Join(workDir, "missing.yaml"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err == nil {
+		t.Errorf("Expected SyncFolder to fail for a missing KLONE_OVERRIDE_FILE")
+	}
+}
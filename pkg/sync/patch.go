@@ -0,0 +1,186 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/download"
+	"github.com/cert-manager/klone/pkg/download/git"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// materialise returns a directory with the content that the destination
+// folder of item should have: the cached upstream content at cachePath with
+// the item's patches applied on top. The cache entry itself is never
+// modified; if there are patches, they are applied to a temporary copy that
+// is removed by the returned cleanup function.
+func materialise(ctx context.Context, workDirPath string, target string, item mod.KloneItem, cachePath string) (string, func(), error) {
+	if len(item.Patches) == 0 {
+		return cachePath, func() {}, nil
+	}
+
+	stagingDir, err := os.MkdirTemp("", "klone-patch-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(stagingDir) }
+
+	contentPath := filepath.Join(stagingDir, "content")
+	if _, err := cache.SyncTree(ctx, cachePath, contentPath); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	for _, patch := range item.Patches {
+		patchPath, err := resolvePatchPath(workDirPath, patch)
+		if err != nil {
+			cleanup()
+			return "", nil, err
+		}
+
+		if err := git.ApplyPatch(ctx, contentPath, patchPath); err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf(
+				"patch %q no longer applies to %s at %s@%s, update it (e.g. fix the files by hand and run \"klone patch %s %s %s\"): %w",
+				patch, filepath.Join(target, item.FolderName), config.RedactURL(item.Location()), item.Pin(), target, item.FolderName, patch, err,
+			)
+		}
+	}
+
+	return contentPath, cleanup, nil
+}
+
+// resolvePatchPath returns the absolute path of a patch file listed in the
+// klone file. Patch files must be stored below the working directory and
+// are never read or written through a symlink.
+func resolvePatchPath(workDirPath string, patch string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(patch)) {
+		return "", fmt.Errorf("invalid patch path %q: must be a relative path below the directory of klone.yaml", patch)
+	}
+	if err := cache.AssertNoSymlinkInSubpath(workDirPath, filepath.FromSlash(patch)); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(workDirPath, filepath.FromSlash(patch)), nil
+}
+
+// CreatePatch writes the local modifications of the item folderName of
+// target, compared to its pinned upstream content, to patchFile and makes
+// it the only patch of that item. Existing patches are already part of the
+// local modifications, so the new patch replaces them.
+func CreatePatch(ctx context.Context, workDirPath string, target string, folderName string, patchFile string) error {
+	workDirPath, err := resolveWorkDir(workDirPath)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = withConfig(ctx, mod.WorkDir(workDirPath))
+	if err != nil {
+		return err
+	}
+
+	patchPath, err := resolvePatchPath(workDirPath, patchFile)
+	if err != nil {
+		return err
+	}
+
+	targets, err := mod.WorkDir(workDirPath).ReadTargets()
+	if err != nil {
+		return fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	selector := mod.Selector{Targets: []string{target}, Folders: []string{folderName}}
+	patch := &bytes.Buffer{}
+	found := false
+	var destPath string
+	if err := forEachItem(workDirPath, targets, func(target string, folderPath string, src mod.KloneItem) error {
+		if !selector.Matches(target, src) {
+			return nil
+		}
+		found = true
+		destPath = folderPath
+
+		if src.Pin() == "" {
+			return fmt.Errorf("%s/%s is not pinned, run \"klone sync\" to pin it", target, src.FolderName)
+		}
+
+		cachePath, err := cache.FetchToCache(ctx, src.KloneSource, download.Get)
+		if err != nil {
+			return err
+		}
+
+		diffDir, err := os.MkdirTemp("", "klone-diff-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(diffDir)
+
+		if _, err := cache.SyncTree(ctx, cachePath, filepath.Join(diffDir, "a")); err != nil {
+			return err
+		}
+		if _, err := cache.SyncTree(ctx, folderPath, filepath.Join(diffDir, "b")); err != nil {
+			return err
+		}
+
+		changed, err := git.Diff(ctx, diffDir, "a", "b", patch)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return fmt.Errorf("%s/%s has no local modifications", target, src.FolderName)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("target %q has no folder %q", target, folderName)
+	}
+
+	// check the patch path again right before it is written
+	if _, err := resolvePatchPath(workDirPath, patchFile); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(patchPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(patchPath, patch.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	// the local modifications are saved in the patch now, so that syncing
+	// no longer overwrites them; the manifest is updated while the klone
+	// file is locked
+	return mod.WorkDir(workDirPath).SetPatches(target, folderName, []string{filepath.ToSlash(filepath.Clean(patchFile))}, func() error {
+		written, err := readManifest(workDirPath)
+		if err != nil {
+			return err
+		}
+		if err := written.record(manifestKey(cleanRelativePath(target), cleanRelativePath(folderName)), destPath); err != nil {
+			return err
+		}
+		return written.write(workDirPath)
+	})
+}
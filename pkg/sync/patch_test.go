@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestSyncFolder_Patches(t *testing.T) {
+	repo, _ := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+	})
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	modPath := filepath.Join(workDir, "make/_shared/go/01_mod.mk")
+	testutil.WriteFiles(t, workDir, map[string]string{"make/_shared/go/01_mod.mk": "local fix\n"})
+
+	if err := CreatePatch(t.Context(), workDir, "make/_shared", "go", "patches/go.patch"); err != nil {
+		t.Fatalf("CreatePatch: %v", err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(workDir, "klone.yaml"))
+	if err != nil {
+		t.Fatalf("read klone.yaml: %v", err)
+	}
+	if !strings.Contains(string(manifest), "patches:\n        - patches/go.patch") {
+		t.Errorf("klone.yaml does not list the patch:\n%s", manifest)
+	}
+
+	drifts, err := VerifyFolder(t.Context(), workDir)
+	if err != nil {
+		t.Fatalf("VerifyFolder: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("VerifyFolder reported drift for a patched folder: %+v", drifts)
+	}
+
+	// A sync re-applies the patch instead of wiping the local fix.
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	if got, err := os.ReadFile(modPath); err != nil || string(got) != "local fix\n" {
+		t.Errorf("patched file = %q, %v; want %q", got, err, "local fix\n")
+	}
+
+	// A patch that no longer applies fails the sync with a clear error.
+	testutil.WriteFiles(t, workDir, map[string]string{"patches/go.patch": strings.ReplaceAll(readFileOrFail(t, filepath.Join(workDir, "patches/go.patch")), "-upstream", "-something else")})
+	_, err = SyncFolder(t.Context(), workDir, Options{})
+	if err == nil || !strings.Contains(err.Error(), `patch "patches/go.patch" no longer applies`) {
+		t.Errorf("SyncFolder with a broken patch = %v, want 'no longer applies' error", err)
+	}
+}
+
+func readFileOrFail(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestCreatePatch_SymlinkRejected(t *testing.T) {
+	testutil.SkipIfNoSymlinks(t)
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  vendor:
+    - folder_name: a
+      local_path: shared/a
+`,
+		"shared/a/a.yaml": "a\n",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	testutil.WriteFiles(t, workDir, map[string]string{"vendor/a/a.yaml": "local fix\n"})
+
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(workDir, "patches")); err != nil {
+		t.Fatalf("plant symlink: %v", err)
+	}
+
+	err := CreatePatch(t.Context(), workDir, "vendor", "a", "patches/a.patch")
+	if err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Errorf("CreatePatch = %v, want symlink-refusal error", err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "a.patch")); !os.IsNotExist(err) {
+		t.Errorf("patch was written through the symlink: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// RemoveFolder removes the item folderName of target (or the whole target
+// if folderName is empty) from the klone file and the sync manifest, deletes
+// its destination folder and removes any directories that are left empty by
+// doing so. Unless force is set, it fails with ErrLocalModifications before
+// anything is deleted if files were modified since sync last wrote them.
+func RemoveFolder(workDirPath string, target string, folderName string, force bool) error {
+	workDirPath, err := resolveWorkDir(workDirPath)
+	if err != nil {
+		return err
+	}
+
+	workDir := mod.WorkDir(workDirPath)
+	return workDir.RemoveTarget(target, folderName, func(target string, removed mod.KloneFolder) error {
+		if err := cache.AssertNoSymlinkInSubpath(workDirPath, target); err != nil {
+			return err
+		}
+
+		// the manifest is read and written while the klone file is locked
+		written, err := readManifest(workDirPath)
+		if err != nil {
+			return err
+		}
+
+		targetRoot := filepath.Join(workDirPath, target)
+		folderPaths := make([]string, len(removed))
+		for i, src := range removed {
+			segments, err := splitFolderName(src.FolderName)
+			if err != nil {
+				return err
+			}
+			canonical := filepath.Join(segments...)
+
+			if err := cache.AssertNoSymlinkInSubpath(targetRoot, canonical); err != nil {
+				return err
+			}
+			folderPaths[i] = filepath.Join(targetRoot, canonical)
+
+			// check all items before any folder is deleted
+			if !force {
+				if err := written.checkDeletedFolder(workDirPath, folderPaths[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		forgotten := false
+		for i, src := range removed {
+			if err := os.RemoveAll(folderPaths[i]); err != nil {
+				return err
+			}
+
+			if err := removeEmptyParents(workDirPath, folderPaths[i]); err != nil {
+				return err
+			}
+
+			key := manifestKey(target, src.FolderName)
+			if _, ok := written.Items[key]; ok {
+				delete(written.Items, key)
+				forgotten = true
+			}
+		}
+
+		if forgotten {
+			return written.write(workDirPath)
+		}
+		return nil
+	})
+}
+
+// removeEmptyParents removes the parent directories of p, up to but not
+// including root, for as long as they are empty.
+func removeEmptyParents(root string, p string) error {
+	for dir := filepath.Dir(p); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			return nil
+		}
+
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+const removeTestManifest = `targets:
+  a/b:
+    - folder_name: c/d
+      repo_url: https://github.com/cert-manager/community.git
+      repo_ref: main
+      repo_hash: 9f0ea0341816665feadcdcfb7744f4245604ab28
+      repo_path: logo
+    - folder_name: e
+      repo_url: https://github.com/cert-manager/community.git
+      repo_ref: main
+      repo_hash: 9f0ea0341816665feadcdcfb7744f4245604ab28
+      repo_path: logo
+`
+
+func TestRemoveFolder(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml":                removeTestManifest,
+		"a/b/c/d/logo.svg":          "logo",
+		"a/b/e/logo.svg":            "logo",
+		"a/SHOULD_NOT_BE_DELETED":   "",
+		"a/b/c/SHOULD_NOT_BE_EMPTY": "",
+	})
+
+	if err := RemoveFolder(workDir, "a/b", "c/d", false); err != nil {
+		t.Fatalf("RemoveFolder: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(workDir, "a/b/c/d")); !os.IsNotExist(err) {
+		t.Errorf("a/b/c/d still exists after removal: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "a/b/c/SHOULD_NOT_BE_EMPTY")); err != nil {
+		t.Errorf("unrelated file next to removed folder was deleted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "a/b/e/logo.svg")); err != nil {
+		t.Errorf("remaining folder was deleted: %v", err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(workDir, "klone.yaml"))
+	if err != nil {
+		t.Fatalf("read klone.yaml: %v", err)
+	}
+	if strings.Contains(string(manifest), "c/d") || !strings.Contains(string(manifest), "folder_name: e") {
+		t.Errorf("klone.yaml not updated correctly:\n%s", manifest)
+	}
+
+	// Removing the whole target deletes its remaining folder and the empty
+	// target directory, but keeps directories that still have content.
+	if err := os.Remove(filepath.Join(workDir, "a/b/c/SHOULD_NOT_BE_EMPTY")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := RemoveFolder(workDir, "a/b", "", false); err != nil {
+		t.Fatalf("RemoveFolder: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "a/b/e")); !os.IsNotExist(err) {
+		t.Errorf("a/b/e still exists after removal: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "a/SHOULD_NOT_BE_DELETED")); err != nil {
+		t.Errorf("unrelated file in parent of target was deleted: %v", err)
+	}
+
+	manifest, err = os.ReadFile(filepath.Join(workDir, "klone.yaml"))
+	if err != nil {
+		t.Fatalf("read klone.yaml: %v", err)
+	}
+	if strings.Contains(string(manifest), "a/b") {
+		t.Errorf("klone.yaml still lists the removed target:\n%s", manifest)
+	}
+}
+
+func TestRemoveFolder_Unknown(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{"klone.yaml": removeTestManifest})
+
+	if err := RemoveFolder(workDir, "a/b", "missing", false); err == nil || !strings.Contains(err.Error(), "has no folder") {
+		t.Errorf("RemoveFolder(unknown folder) = %v, want 'has no folder' error", err)
+	}
+	if err := RemoveFolder(workDir, "x", "", false); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("RemoveFolder(unknown target) = %v, want 'does not exist' error", err)
+	}
+}
+
+func TestRemoveFolder_SymlinkRejected(t *testing.T) {
+	testutil.SkipIfNoSymlinks(t)
+	sb := t.TempDir()
+	workDir := filepath.Join(sb, "project")
+	decoy := filepath.Join(sb, "decoy")
+	testutil.WriteFiles(t, workDir, map[string]string{"klone.yaml": removeTestManifest})
+	testutil.WriteFiles(t, decoy, map[string]string{"logo.svg": "VICTIM"})
+
+	if err := os.MkdirAll(filepath.Join(workDir, "a/b"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Symlink(decoy, filepath.Join(workDir, "a/b/e")); err != nil {
+		t.Fatalf("plant symlink: %v", err)
+	}
+
+	err := RemoveFolder(workDir, "a/b", "e", false)
+	if err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Errorf("RemoveFolder = %v, want symlink-refusal error", err)
+	}
+	if _, err := os.Stat(filepath.Join(decoy, "logo.svg")); err != nil {
+		t.Errorf("decoy sentinel was removed through symlink: %v", err)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(workDir, "klone.yaml"))
+	if err != nil {
+		t.Fatalf("read klone.yaml: %v", err)
+	}
+	if !strings.Contains(string(manifest), "folder_name: e") {
+		t.Errorf("klone.yaml was modified although removal failed:\n%s", manifest)
+	}
+}
+
+func TestRemoveFolder_Manifest(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  vendor:
+    - folder_name: a
+      local_path: shared/a
+    - folder_name: b
+      local_path: shared/b
+`,
+		"shared/a/a.yaml": "a\n",
+		"shared/b/b.yaml": "b\n",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	if err := RemoveFolder(workDir, "vendor", "a", false); err != nil {
+		t.Fatalf("RemoveFolder: %v", err)
+	}
+
+	written, err := readManifest(workDir)
+	if err != nil {
+		t.Fatalf("readManifest: %v", err)
+	}
+	if _, ok := written.Items["vendor/a"]; ok {
+		t.Errorf("Expected the manifest entry of the removed folder to be removed")
+	}
+	if _, ok := written.Items["vendor/b"]; !ok {
+		t.Errorf("Expected the manifest entry of the remaining folder to be kept")
+	}
+}
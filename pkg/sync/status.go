@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/download"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// LocalState describes how a kloned folder relates to its pinned content
+// in the klone cache.
+type LocalState string
+
+const (
+	// StateInSync means the folder matches the cached pinned content.
+	StateInSync LocalState = "in-sync"
+	// StateDrifted means the folder differs from the cached pinned content.
+	StateDrifted LocalState = "drifted"
+	// StateMissing means the folder does not exist.
+	StateMissing LocalState = "missing"
+	// StateNotCached means the pinned content is not in the klone cache, so
+	// the folder could not be compared without downloading it.
+	StateNotCached LocalState = "not-cached"
+	// StateUnpinned means the item has no repo_hash or sha256 yet.
+	StateUnpinned LocalState = "unpinned"
+)
+
+type ItemStatus struct {
+	Target     string     `json:"target"`
+	FolderName string     `json:"folder_name"`
+	RepoURL    string     `json:"repo_url"`
+	RepoRef    string     `json:"repo_ref"`
+	RepoHash   string     `json:"repo_hash"`
+	RepoTag    string     `json:"repo_tag,omitempty"`
+	RepoPath   string     `json:"repo_path"`
+	State      LocalState `json:"state"`
+
+	// LatestHash, LatestTag and Outdated are only set when the remote was
+	// queried.
+	LatestHash string `json:"latest_hash,omitempty"`
+	LatestTag  string `json:"latest_tag,omitempty"`
+	Outdated   *bool  `json:"outdated,omitempty"`
+}
+
+// Status reports every item in the klone file of workDirPath and whether
+// its folder is in sync with the cached pinned content. Nothing is
+// downloaded, unless remote is set, in which case the latest hash of every
+// repo_ref is looked up too.
+func Status(ctx context.Context, workDirPath string, remote bool) ([]ItemStatus, error) {
+	workDirPath, err := resolveWorkDir(workDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = withConfig(ctx, mod.WorkDir(workDirPath))
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := mod.WorkDir(workDirPath).ReadTargets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	var statuses []ItemStatus
+	if err := forEachItem(workDirPath, targets, func(target string, folderPath string, src mod.KloneItem) error {
+		state, err := localState(ctx, workDirPath, target, folderPath, src)
+		if err != nil {
+			return err
+		}
+
+		status := ItemStatus{
+			Target:     target,
+			FolderName: src.FolderName,
+			RepoURL:    config.RedactURL(src.Location()),
+			RepoRef:    src.RepoRef,
+			RepoHash:   src.Pin(),
+			RepoTag:    src.RepoTag,
+			RepoPath:   src.RepoPath,
+			State:      state,
+		}
+
+		// archives are pinned by their sha256 and have no newer version
+		if remote && src.Kind() == mod.SourceArchive {
+			outdated := false
+			status.Outdated = &outdated
+		} else if remote {
+			latest, err := download.ResolveRef(ctx, src.KloneSource)
+			if err != nil {
+				return err
+			}
+
+			outdated := latest.Hash != src.RepoHash
+			status.LatestHash = latest.Hash
+			status.LatestTag = latest.Tag
+			status.Outdated = &outdated
+		}
+
+		statuses = append(statuses, status)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+func localState(ctx context.Context, workDirPath string, target string, folderPath string, src mod.KloneItem) (LocalState, error) {
+	if src.Pin() == "" {
+		return StateUnpinned, nil
+	}
+
+	if _, err := os.Stat(folderPath); os.IsNotExist(err) {
+		return StateMissing, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	cachePath, ok, err := cache.Lookup(ctx, src.KloneSource)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return StateNotCached, nil
+	}
+
+	contentPath, cleanup, err := materialise(ctx, workDirPath, target, src, cachePath)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	diffs, err := cache.CompareTrees(contentPath, folderPath)
+	if err != nil {
+		return "", err
+	}
+	if len(diffs) > 0 {
+		return StateDrifted, nil
+	}
+
+	return StateInSync, nil
+}
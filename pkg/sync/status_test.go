@@ -0,0 +1,123 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestStatus(t *testing.T) {
+	repo, hash := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+	})
+
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_hash: ` + hash + `
+      repo_path: modules/go
+    - folder_name: missing
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_hash: ` + hash + `
+      repo_path: modules/go
+    - folder_name: unpinned
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`,
+		"make/_shared/go/01_mod.mk": "upstream\n",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	assertStates := func(want map[string]LocalState) {
+		t.Helper()
+		statuses, err := Status(t.Context(), workDir, false)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		if len(statuses) != len(want) {
+			t.Fatalf("Status returned %d items, want %d", len(statuses), len(want))
+		}
+		for _, status := range statuses {
+			if status.State != want[status.FolderName] {
+				t.Errorf("Status(%s).State = %q, want %q", status.FolderName, status.State, want[status.FolderName])
+			}
+			if status.Outdated != nil {
+				t.Errorf("Status(%s).Outdated set without remote lookup", status.FolderName)
+			}
+		}
+	}
+
+	assertStates(map[string]LocalState{"go": StateNotCached, "missing": StateMissing, "unpinned": StateUnpinned})
+
+	// VerifyFolder populates the cache for the pinned items before it
+	// reaches the unpinned one
+	if _, err := VerifyFolder(t.Context(), workDir); err == nil {
+		t.Fatalf("VerifyFolder with an unpinned item returned nil, want error")
+	}
+	assertStates(map[string]LocalState{"go": StateInSync, "missing": StateMissing, "unpinned": StateUnpinned})
+
+	testutil.WriteFiles(t, workDir, map[string]string{"make/_shared/go/01_mod.mk": "local edit\n"})
+	assertStates(map[string]LocalState{"go": StateDrifted, "missing": StateMissing, "unpinned": StateUnpinned})
+
+	statuses, err := Status(t.Context(), workDir, true)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		wantOutdated := status.FolderName == "unpinned"
+		if status.LatestHash != hash || status.Outdated == nil || *status.Outdated != wantOutdated {
+			t.Errorf("Status(%s) remote = %q/%v, want %q/%v", status.FolderName, status.LatestHash, status.Outdated, hash, wantOutdated)
+		}
+	}
+}
+
+func TestStatus_RemoteArchive(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  vendor:
+    - folder_name: archive
+      archive_url: https://token@example.com/archive.tar.gz
+      sha256: ` + strings.Repeat("0", 64) + `
+      repo_path: .
+`,
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	// archives are pinned by their sha256, so they are never looked up and
+	// never outdated
+	statuses, err := Status(t.Context(), workDir, true)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].LatestHash != "" || statuses[0].Outdated == nil || *statuses[0].Outdated {
+		t.Errorf("Status() = %+v, want a single archive that is not outdated", statuses)
+	}
+	if want := "https://xxxxx@example.com/archive.tar.gz"; len(statuses) == 1 && statuses[0].RepoURL != want {
+		t.Errorf("Expected the repo_url to be redacted to %q, but got %q", want, statuses[0].RepoURL)
+	}
+}
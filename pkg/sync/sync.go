@@ -19,43 +19,292 @@ package sync
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	gosync "sync"
 
 	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/config"
+	"github.com/cert-manager/klone/pkg/download"
 	"github.com/cert-manager/klone/pkg/download/git"
+	"github.com/cert-manager/klone/pkg/download/local"
+	"github.com/cert-manager/klone/pkg/internal/pathutil"
+	"github.com/cert-manager/klone/pkg/logging"
 	"github.com/cert-manager/klone/pkg/mod"
 )
 
-func SyncFolder(ctx context.Context, workDirPath string, forceUpgrade bool) error {
-	// AssertNoSymlinkInSubpath treats workDirPath as a trusted root and
-	// does not inspect it. Resolve symlinks once up-front so a caller
-	// invoking klone from inside a symlinked path cannot shift the trust
-	// boundary above the intended directory.
-	resolved, err := filepath.EvalSymlinks(workDirPath)
+type Options struct {
+	// ForceUpgrade resolves the latest hash of every selected item, even if
+	// it is already pinned, and pins its content hash again.
+	ForceUpgrade bool
+	// Selector limits the sync to a subset of the items. Items that are not
+	// selected are neither resolved nor copied.
+	Selector mod.Selector
+	// DryRun computes the Report without modifying the destination folders
+	// or the klone file. Pinned content is still downloaded to the klone
+	// cache, as it is needed to know which files would change.
+	DryRun bool
+	// Jobs is the maximum number of items whose hash is resolved and whose
+	// content is downloaded to the klone cache concurrently. Destination
+	// folders are always written one at a time, in a stable order. If it is
+	// not positive, the jobs of the klone config are used.
+	Jobs int
+	// Force overwrites files in the destination folders that were modified
+	// or added since sync last wrote them, and deletes the folders of items
+	// that are no longer in the klone file even if they were modified.
+	// Without it, SyncFolder fails with ErrLocalModifications instead.
+	Force bool
+	// CommitLog collects, for every git item whose repo_hash changes, the
+	// upstream commits between the old and new hash that touch its
+	// repo_path. Failing to collect them only prints a warning.
+	CommitLog bool
+}
+
+// Report describes what SyncFolder changed, or would change when
+// Options.DryRun is set.
+type Report struct {
+	DryRun bool `json:"dry_run"`
+	// Removed lists the paths, relative to the working directory, that were
+	// removed because no item refers to them anymore.
+	Removed []string `json:"removed"`
+	// Items lists every selected item. If SyncFolder fails, it lists the
+	// items that were synced and the items that failed.
+	Items []ItemReport `json:"items"`
+}
+
+type ItemReport struct {
+	Target     string `json:"target"`
+	FolderName string `json:"folder_name"`
+	OldHash    string `json:"old_hash"`
+	NewHash    string `json:"new_hash"`
+	// NewTag is the tag selected by a semver constraint or tag glob.
+	NewTag string `json:"new_tag,omitempty"`
+	// Override is the local directory the item was kloned from instead of
+	// its upstream, if it is replaced in the override file.
+	Override string `json:"override,omitempty"`
+	// CacheHit is set if the pinned content was already in the klone cache.
+	CacheHit bool `json:"cache_hit"`
+	// Commits lists the upstream commits between OldHash and NewHash that
+	// touch repo_path, newest first, if Options.CommitLog is set. It is nil
+	// if the commits could not be listed.
+	Commits []git.Commit `json:"commits,omitempty"`
+	// Changes lists the changes that syncing made to the destination folder
+	// or, for dry runs, would make.
+	Changes []Change `json:"changes"`
+	// Error is the reason the item could not be synced.
+	Error string `json:"error,omitempty"`
+}
+
+// ChangeKind describes what syncing does to a path in a destination folder.
+type ChangeKind string
+
+const (
+	// ChangeCreate means the path only exists in the pinned content.
+	ChangeCreate ChangeKind = "create"
+	// ChangeUpdate means the path differs from the pinned content.
+	ChangeUpdate ChangeKind = "update"
+	// ChangeDelete means the path does not exist in the pinned content.
+	ChangeDelete ChangeKind = "delete"
+)
+
+type Change struct {
+	// Path is the slash-separated path relative to the destination folder.
+	Path string     `json:"path"`
+	Kind ChangeKind `json:"kind"`
+}
+
+// changesOf returns the changes that revert diffs, which describe the
+// destination relative to the pinned content.
+func changesOf(diffs []cache.Diff) []Change {
+	if diffs == nil {
+		return nil
+	}
+
+	changes := make([]Change, len(diffs))
+	for i, diff := range diffs {
+		changes[i].Path = diff.Path
+		switch diff.Kind {
+		case cache.DiffAdded:
+			changes[i].Kind = ChangeDelete
+		case cache.DiffRemoved:
+			changes[i].Kind = ChangeCreate
+		case cache.DiffModified:
+			changes[i].Kind = ChangeUpdate
+		}
+	}
+	return changes
+}
+
+// SyncFolder copies the pinned content of the selected items of the klone
+// file in workDirPath to their folders. If it fails after the items were
+// read, the returned Report describes the items that were synced and the
+// ItemReport.Error of the items that failed.
+func SyncFolder(ctx context.Context, workDirPath string, opts Options) (*Report, error) {
+	workDirPath, err := resolveWorkDir(workDirPath)
 	if err != nil {
-		return fmt.Errorf("failed to resolve workDir %q: %w", workDirPath, err)
+		return nil, err
+	}
+
+	ctx, err = withConfig(ctx, mod.WorkDir(workDirPath))
+	if err != nil {
+		return nil, err
 	}
-	workDirPath = resolved
 
 	workDir := mod.WorkDir(workDirPath)
+	logger := logging.FromContext(ctx)
+
+	if opts.Jobs <= 0 {
+		cfg, err := config.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts.Jobs = cfg.Jobs
+	}
+
+	// the manifest is only read and written while the klone file is
+	// locked, so that concurrent runs do not lose each other's entries
+	readWritten := gosync.OnceValues(func() (*manifest, error) {
+		return readManifest(workDirPath)
+	})
+
+	overrides, err := workDir.ReadOverrides()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overrides: %w", err)
+	}
+	if overrides != nil {
+		logger.Warn("overrides are active, the overridden folders will not match klone.yaml", "override_file", overrides.Path)
+	}
+
+	report := &Report{DryRun: opts.DryRun}
+	oldHashes := map[[2]string]string{}
+	overridden := map[[2]string]mod.KloneSource{}
+	commits := map[[2]string][]git.Commit{}
+	cacheHits := map[[2]string]bool{}
+	var mu gosync.Mutex
+
 	if err := workDir.FetchTargets(
-		func(_ string, _ string, src *mod.KloneSource) error {
-			src.RepoPath = filepath.Join(".", filepath.Clean(filepath.Join("/", src.RepoPath)))
+		mod.FetchOptions{
+			Selector: opts.Selector,
+			DryRun:   opts.DryRun,
+			Jobs:     opts.Jobs,
+		},
+		func(target string, folderName string, src *mod.KloneSource) error {
+			oldHash := src.Pin()
+			mu.Lock()
+			oldHashes[[2]string{target, folderName}] = oldHash
+			mu.Unlock()
+
+			// reject unsafe destinations before anything is downloaded, they
+			// are checked again right before the destination is written
+			segments, err := splitFolderName(folderName)
+			if err != nil {
+				return err
+			}
+			if err := cache.AssertNoSymlinkInSubpath(workDirPath, filepath.Join(target, filepath.Join(segments...))); err != nil {
+				return err
+			}
 
-			if src.RepoHash == "" || forceUpgrade {
-				hash, err := git.GetHash(ctx, src.RepoURL, src.RepoRef)
+			// check all items before any destination folder is written
+			if !opts.Force {
+				written, err := readWritten()
 				if err != nil {
 					return err
 				}
+				key := manifestKey(target, folderName)
+				modified, err := written.localModifications(key, filepath.Join(workDirPath, target, filepath.Join(segments...)))
+				if err != nil {
+					return err
+				}
+				if len(modified) > 0 {
+					return localModificationsError(key, modified)
+				}
+			}
+
+			src.RepoPath = cleanRelativePath(src.RepoPath)
 
-				src.RepoHash = hash
+			// src is left untouched for overridden items, so that the
+			// override is never written to klone.yaml
+			if override, ok := overrides.Lookup(target, mod.KloneItem{FolderName: folderName, KloneSource: *src}); ok {
+				resolved, err := download.ResolveRef(ctx, override)
+				if err != nil {
+					return err
+				}
+				override.RepoHash = resolved.Hash
+
+				_, hit, err := fetchToCache(ctx, override)
+				if err != nil {
+					return err
+				}
+
+				mu.Lock()
+				overridden[[2]string{target, folderName}] = override
+				cacheHits[[2]string{target, folderName}] = hit
+				mu.Unlock()
+				return nil
 			}
 
-			return nil
+			// a pinned local path must still have its pinned content, a
+			// cache hit would hide any change
+			if src.Kind() == mod.SourceLocal && src.RepoHash != "" && !opts.ForceUpgrade {
+				current, err := download.ResolveRef(ctx, *src)
+				if err != nil {
+					return err
+				}
+				if current.Hash != src.RepoHash {
+					return local.ChangedError{LocalPath: src.LocalPath, Pinned: src.RepoHash, Current: current.Hash}
+				}
+			}
+
+			// archives are pinned by their sha256, which is never resolved;
+			// upgrading them only pins the content of the current sha256
+			if src.Kind() == mod.SourceArchive {
+				if opts.ForceUpgrade {
+					src.ContentHash = ""
+				}
+			} else if src.RepoHash == "" || opts.ForceUpgrade {
+				resolved, err := download.ResolveRef(ctx, *src)
+				if err != nil {
+					return err
+				}
+
+				src.RepoHash = resolved.Hash
+				src.RepoTag = resolved.Tag
+				src.ContentHash = ""
+
+				if opts.CommitLog && src.Kind() == mod.SourceGit && oldHash != "" && oldHash != src.RepoHash {
+					log, err := git.Log(ctx, src.RepoURL, oldHash, src.RepoHash, src.RepoPath)
+					if err != nil {
+						logger.Warn("failed to list the upstream commits", "repo_url", config.RedactURL(src.RepoURL), "from", oldHash, "to", src.RepoHash, "error", err)
+					} else if log == nil {
+						log = []git.Commit{}
+					}
+
+					mu.Lock()
+					commits[[2]string{target, folderName}] = log
+					mu.Unlock()
+				}
+			}
+
+			// populate the cache now, so that downloads run concurrently;
+			// syncItem then finds the content in the cache
+			cachePath, hit, err := fetchToCache(ctx, *src)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			cacheHits[[2]string{target, folderName}] = hit
+			mu.Unlock()
+
+			if src.ContentHash == "" {
+				src.ContentHash, err = cache.HashTree(cachePath)
+			}
+			return err
 		},
-		func(target string, srcs mod.KloneFolder) error {
+		func(target string, srcs mod.KloneFolder, selected []bool) error {
 			canonical := make([]string, len(srcs))
 			folders := newTreeNode()
 			for i, src := range srcs {
@@ -71,8 +320,10 @@ func SyncFolder(ctx context.Context, workDirPath string, forceUpgrade bool) erro
 				return err
 			}
 
-			if err := os.MkdirAll(filepath.Join(workDirPath, target), 0755); err != nil {
-				return err
+			if !opts.DryRun {
+				if err := os.MkdirAll(filepath.Join(workDirPath, target), 0755); err != nil {
+					return err
+				}
 			}
 
 			targetRoot := filepath.Join(workDirPath, target)
@@ -83,7 +334,7 @@ func SyncFolder(ctx context.Context, workDirPath string, forceUpgrade bool) erro
 			// workDir/vendored/a -> /etc, left over from a compromised state
 			// prior to this fix) would otherwise have its target's entries
 			// deleted by os.RemoveAll on the first post-fix run. The same
-			// walk also covers the in-tree (safe-by-rsync) symlink that an
+			// walk also covers the in-tree (safe) symlink that an
 			// earlier iteration may have planted to redirect later writes.
 			for i := range srcs {
 				if err := cache.AssertNoSymlinkInSubpath(targetRoot, canonical[i]); err != nil {
@@ -91,30 +342,187 @@ func SyncFolder(ctx context.Context, workDirPath string, forceUpgrade bool) erro
 				}
 			}
 
-			// 1) Remove all folders that are not defined in srcs
-			if err := folders.Cleanup(targetRoot); err != nil {
+			written, err := readWritten()
+			if err != nil {
 				return err
 			}
 
-			// 2) Sync all folders with cached files
-			for i, src := range srcs {
-				if err := cache.CloneWithCache(ctx, filepath.Join(targetRoot, canonical[i]), src.KloneSource, git.Get); err != nil {
+			// 1) Remove all folders that are not defined in srcs, unless
+			// klone wrote them and they were modified since
+			var removed []string
+			if !opts.Force {
+				if err := folders.cleanup(targetRoot, true, &removed); err != nil {
 					return err
 				}
+				for _, p := range removed {
+					if err := written.checkDeletedFolder(workDirPath, p); err != nil {
+						return err
+					}
+				}
+				removed = nil
+			}
+			if err := folders.cleanup(targetRoot, opts.DryRun, &removed); err != nil {
+				return err
+			}
+			for _, p := range removed {
+				rel, err := filepath.Rel(workDirPath, p)
+				if err != nil {
+					return err
+				}
+				report.Removed = append(report.Removed, rel)
+
+				if !opts.DryRun {
+					written.forget(filepath.ToSlash(rel))
+				}
+			}
+
+			// 2) Sync all selected folders with cached files
+			for i, src := range srcs {
+				if !selected[i] {
+					continue
+				}
+
+				item := ItemReport{
+					Target:     target,
+					FolderName: src.FolderName,
+					OldHash:    oldHashes[[2]string{target, src.FolderName}],
+					NewHash:    src.Pin(),
+					NewTag:     src.RepoTag,
+					CacheHit:   cacheHits[[2]string{target, src.FolderName}],
+					Commits:    commits[[2]string{target, src.FolderName}],
+				}
+
+				if override, ok := overridden[[2]string{target, src.FolderName}]; ok {
+					logger.Warn("folder is kloned from an override instead of its upstream, do not commit it",
+						"folder", filepath.Join(target, src.FolderName), "override", override.LocalPath, "upstream", config.RedactURL(src.Location()), "pin", src.Pin())
+
+					src.KloneSource = override
+					item.Override = override.LocalPath
+				}
+
+				destPath := filepath.Join(targetRoot, canonical[i])
+				if err := syncItem(ctx, workDirPath, target, src, destPath, opts.DryRun, &item); err != nil {
+					item.Error = err.Error()
+					report.Items = append(report.Items, item)
+					return &mod.ItemError{Target: target, FolderName: src.FolderName, Err: err}
+				}
+
+				// record every item right after it was written, so that a
+				// later failure does not make it look locally modified
+				if !opts.DryRun {
+					if err := written.record(manifestKey(target, src.FolderName), destPath); err != nil {
+						return err
+					}
+					if err := written.write(workDirPath); err != nil {
+						return fmt.Errorf("failed to write the manifest of written files: %w", err)
+					}
+				}
+
+				report.Items = append(report.Items, item)
 			}
 
 			return nil
 		},
 	); err != nil {
-		return fmt.Errorf("failed to fetch targets: %w", err)
+		// report the items that failed before any folder was written
+		for _, itemErr := range mod.ItemErrors(err) {
+			key := [2]string{itemErr.Target, itemErr.FolderName}
+			if slices.ContainsFunc(report.Items, func(item ItemReport) bool {
+				return item.Target == key[0] && item.FolderName == key[1]
+			}) {
+				continue
+			}
+
+			report.Items = append(report.Items, ItemReport{
+				Target:     itemErr.Target,
+				FolderName: itemErr.FolderName,
+				OldHash:    oldHashes[key],
+				Error:      itemErr.Err.Error(),
+			})
+		}
+
+		return report, fmt.Errorf("failed to fetch targets: %w", err)
 	}
 
-	if err := cache.CleanupOldCacheItems(); err != nil {
-		return fmt.Errorf("failed to cleanup old cache items: %w", err)
+	if opts.DryRun {
+		return report, nil
 	}
 
-	return nil
+	if err := cache.CleanupOldCacheItems(ctx); err != nil {
+		return report, fmt.Errorf("failed to cleanup old cache items: %w", err)
+	}
 
+	return report, nil
+}
+
+// fetchToCache calls cache.FetchToCache and also returns whether the
+// content of src was already cached.
+func fetchToCache(ctx context.Context, src mod.KloneSource) (string, bool, error) {
+	_, hit, err := cache.Lookup(ctx, src)
+	if err != nil {
+		return "", false, err
+	}
+
+	cachePath, err := cache.FetchToCache(ctx, src, download.Get)
+	return cachePath, hit, err
+}
+
+// syncItem copies the pinned content of src, with its patches applied, to
+// destPath and records the changes in report. With dryRun set, the changes
+// that copying would make are only recorded.
+func syncItem(ctx context.Context, workDirPath string, target string, src mod.KloneItem, destPath string, dryRun bool, report *ItemReport) error {
+	cachePath, err := cache.FetchToCache(ctx, src.KloneSource, download.Get)
+	if err != nil {
+		return err
+	}
+
+	contentPath, cleanup, err := materialise(ctx, workDirPath, target, src, cachePath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var diffs []cache.Diff
+	if dryRun {
+		diffs, err = cache.CompareTrees(contentPath, destPath)
+	} else {
+		diffs, err = cache.SyncTree(ctx, contentPath, destPath)
+	}
+	report.Changes = changesOf(diffs)
+	return err
+}
+
+// resolveWorkDir resolves all symlinks in workDirPath. AssertNoSymlinkInSubpath
+// treats workDirPath as a trusted root and does not inspect it. Resolve
+// symlinks once up-front so a caller invoking klone from inside a symlinked
+// path cannot shift the trust boundary above the intended directory.
+func resolveWorkDir(workDirPath string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(workDirPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workDir %q: %w", workDirPath, err)
+	}
+	return resolved, nil
+}
+
+// withConfig returns a copy of ctx that carries the klone config, which is
+// loaded once unless ctx already carries it, and the mirrors of the klone
+// file in workDirPath, which are applied when git items are fetched.
+func withConfig(ctx context.Context, workDir mod.WorkDir) (context.Context, error) {
+	cfg, err := config.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrors, err := workDir.ReadMirrors()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirrors: %w", err)
+	}
+
+	return git.WithMirrors(config.IntoContext(ctx, cfg), mirrors), nil
+}
+
+func cleanRelativePath(src string) string {
+	return filepath.Join(".", filepath.Clean(filepath.Join("/", src)))
 }
 
 type treeNode struct {
@@ -142,7 +550,15 @@ func (tn *treeNode) Add(pathSegments ...string) {
 	tn.children[pathSegments[0]].Add(pathSegments[1:]...)
 }
 
+// Cleanup removes all entries below root that are not part of the tree.
 func (tn treeNode) Cleanup(root string) error {
+	var removed []string
+	return tn.cleanup(root, false, &removed)
+}
+
+// cleanup implements Cleanup and appends every removed entry to removed.
+// With dryRun set, the entries are only collected and not removed.
+func (tn treeNode) cleanup(root string, dryRun bool, removed *[]string) error {
 	if tn.isLeaf {
 		return nil
 	}
@@ -181,13 +597,19 @@ func (tn treeNode) Cleanup(root string) error {
 			return fmt.Errorf("treeNode.Cleanup: refusing to remove symlink entry %q (VC-53818)", entryPath)
 		}
 
+		*removed = append(*removed, entryPath)
+
+		if dryRun {
+			continue
+		}
+
 		if err := os.RemoveAll(entryPath); err != nil {
 			return err
 		}
 	}
 
-	for name, node := range tn.children {
-		if err := node.Cleanup(filepath.Join(root, name)); err != nil {
+	for _, name := range slices.Sorted(maps.Keys(tn.children)) {
+		if err := tn.children[name].cleanup(filepath.Join(root, name), dryRun, removed); err != nil {
 			return err
 		}
 	}
@@ -206,7 +628,7 @@ func splitFolderName(folderName string) ([]string, error) {
 	if folderName == "" {
 		return nil, fmt.Errorf("invalid folder_name %q: empty", folderName)
 	}
-	if hasWindowsDrivePrefix(folderName) {
+	if pathutil.HasWindowsDrivePrefix(folderName) {
 		return nil, fmt.Errorf("invalid folder_name %q: Windows volume prefix is not allowed", folderName)
 	}
 	if filepath.IsAbs(folderName) || filepath.VolumeName(folderName) != "" {
@@ -224,13 +646,3 @@ func splitFolderName(folderName string) ([]string, error) {
 	}
 	return segments, nil
 }
-
-// hasWindowsDrivePrefix catches drive-qualified paths on every GOOS;
-// filepath.VolumeName only recognises the shape when GOOS=windows.
-func hasWindowsDrivePrefix(s string) bool {
-	if len(s) < 2 || s[1] != ':' {
-		return false
-	}
-	c := s[0]
-	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
-}
@@ -19,29 +19,21 @@ package sync
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
-)
 
-// skipIfNoSymlinks probes whether the current process/OS can create a
-// symlink and skips the test if it cannot. Windows without Developer Mode
-// (or sandboxed CI without the symlink privilege) returns EPERM/ENOTSUP
-// from os.Symlink, which would otherwise mask the real assertion under a
-// setup failure.
-func skipIfNoSymlinks(t *testing.T) {
-	t.Helper()
-	probe := t.TempDir()
-	if err := os.Symlink(probe, filepath.Join(probe, "probe")); err != nil {
-		t.Skipf("skip: symlinks unsupported in this environment: %v", err)
-	}
-}
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+	"github.com/cert-manager/klone/pkg/mod"
+)
 
 // TestSyncFolder_TargetSymlinkRejected is the regression for the VC-53816
 // root-symlink variant: when the target directory itself (e.g. `vendored`)
 // is a pre-planted symlink to an attacker-chosen location, SyncFolder must
-// refuse rather than letting Cleanup/MkdirAll/rsync dereference it.
+// refuse rather than letting Cleanup/MkdirAll/SyncTree dereference it.
 func TestSyncFolder_TargetSymlinkRejected(t *testing.T) {
-	skipIfNoSymlinks(t)
+	testutil.SkipIfNoSymlinks(t)
 	sb := t.TempDir()
 	workDir := filepath.Join(sb, "project")
 	decoy := filepath.Join(sb, "decoy")
@@ -74,7 +66,7 @@ func TestSyncFolder_TargetSymlinkRejected(t *testing.T) {
 	}
 
 	t.Setenv("KLONE_CACHE_DIR", filepath.Join(sb, "cache"))
-	err := SyncFolder(t.Context(), workDir, false)
+	_, err := SyncFolder(t.Context(), workDir, Options{})
 	if err == nil {
 		t.Fatalf("SyncFolder returned nil, want symlink-refusal error")
 	}
@@ -249,7 +241,7 @@ func TestSyncFolder_ColonPayloadDoesNotEscape(t *testing.T) {
 	t.Setenv("KLONE_CACHE_DIR", filepath.Join(sb, "cache"))
 	// Bogus repo means SyncFolder must report a non-nil error. The real
 	// CVE proof is that the sentinels above the working dir survive.
-	if err := SyncFolder(t.Context(), victim, false); err == nil {
+	if _, err := SyncFolder(t.Context(), victim, Options{}); err == nil {
 		t.Fatalf("SyncFolder returned nil for bogus manifest, want error")
 	}
 
@@ -259,3 +251,171 @@ func TestSyncFolder_ColonPayloadDoesNotEscape(t *testing.T) {
 		}
 	}
 }
+
+func TestSyncFolder_DryRun(t *testing.T) {
+	repo, hash := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+		"modules/go/new.mk":    "new\n",
+	})
+
+	workDir := t.TempDir()
+	manifest := `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_path: modules/go
+`
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml":                   manifest,
+		"make/_shared/go/01_mod.mk":    "local edit\n",
+		"make/_shared/go/stale.mk":     "stale\n",
+		"make/_shared/old/01_mod.mk":   "old\n",
+		"make/SHOULD_NOT_BE_DELETED":   "",
+		"make/_shared/.SHOULD_BE_GONE": "",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	report, err := SyncFolder(t.Context(), workDir, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	wantRemoved := []string{"make/_shared/.SHOULD_BE_GONE", "make/_shared/old"}
+	if !slices.Equal(report.Removed, wantRemoved) {
+		t.Errorf("report.Removed = %v, want %v", report.Removed, wantRemoved)
+	}
+
+	if len(report.Items) != 1 {
+		t.Fatalf("report.Items has %d items, want 1", len(report.Items))
+	}
+	item := report.Items[0]
+	if item.OldHash != "" || item.NewHash != hash {
+		t.Errorf("report hash change = %q -> %q, want \"\" -> %q", item.OldHash, item.NewHash, hash)
+	}
+	wantChanges := []Change{
+		{Path: "01_mod.mk", Kind: ChangeUpdate},
+		{Path: "new.mk", Kind: ChangeCreate},
+		{Path: "stale.mk", Kind: ChangeDelete},
+	}
+	if !slices.Equal(item.Changes, wantChanges) {
+		t.Errorf("report changes = %v, want %v", item.Changes, wantChanges)
+	}
+
+	// Nothing may have been modified.
+	got, err := os.ReadFile(filepath.Join(workDir, "klone.yaml"))
+	if err != nil {
+		t.Fatalf("read klone.yaml: %v", err)
+	}
+	if string(got) != manifest {
+		t.Errorf("dry run modified klone.yaml:\n%s", got)
+	}
+	for _, p := range []string{"make/_shared/old/01_mod.mk", "make/_shared/go/stale.mk", "make/_shared/.SHOULD_BE_GONE"} {
+		if _, err := os.Stat(filepath.Join(workDir, p)); err != nil {
+			t.Errorf("dry run removed %s: %v", p, err)
+		}
+	}
+}
+
+func TestSyncFolder_Report(t *testing.T) {
+	workDir := t.TempDir()
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml": `targets:
+  vendor:
+    - folder_name: a
+      local_path: shared/a
+    - folder_name: b
+      local_path: shared/b
+`,
+		"shared/a/a.yaml": "a\n",
+		"shared/b/b.yaml": "b\n",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	report, err := SyncFolder(t.Context(), workDir, Options{})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	for _, item := range report.Items {
+		want := []Change{{Path: item.FolderName + ".yaml", Kind: ChangeCreate}}
+		if item.CacheHit || !slices.Equal(item.Changes, want) || item.Error != "" {
+			t.Errorf("Expected a cache miss that creates one file, but got %+v", item)
+		}
+	}
+
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"vendor/a/a.yaml":     "edited\n",
+		"vendor/b/extra.yaml": "extra\n",
+	})
+
+	report, err = SyncFolder(t.Context(), workDir, Options{Force: true})
+	if err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+	wantChanges := map[string][]Change{
+		"a": {{Path: "a.yaml", Kind: ChangeUpdate}},
+		"b": {{Path: "extra.yaml", Kind: ChangeDelete}},
+	}
+	for _, item := range report.Items {
+		if !item.CacheHit || !slices.Equal(item.Changes, wantChanges[item.FolderName]) {
+			t.Errorf("Expected a cache hit with changes %v, but got %+v", wantChanges[item.FolderName], item)
+		}
+	}
+
+	testutil.WriteFiles(t, workDir, map[string]string{"shared/b/b.yaml": "changed\n"})
+
+	report, err = SyncFolder(t.Context(), workDir, Options{})
+	if err == nil {
+		t.Fatalf("Expected SyncFolder to fail after the local path changed")
+	}
+	if report == nil || len(report.Items) != 1 {
+		t.Fatalf("Expected a report of the failed item, but got %+v", report)
+	}
+	if item := report.Items[0]; item.FolderName != "b" || item.OldHash == "" || !strings.Contains(item.Error, "shared/b") {
+		t.Errorf("Expected the report to contain the error of vendor/b, but got %+v", item)
+	}
+}
+
+func TestSyncFolder_Mirrors(t *testing.T) {
+	repo, hash := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+	})
+
+	workDir := t.TempDir()
+	manifest := `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: https://git.example.invalid/` + filepath.Base(repo) + `
+      repo_ref: main
+      repo_path: modules/go
+mirrors:
+  - url: ` + filepath.Dir(repo) + `/
+    instead_of: https://git.example.invalid/
+`
+	testutil.WriteFiles(t, workDir, map[string]string{"klone.yaml": manifest})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+	t.Setenv("KLONE_MIRRORS", "")
+
+	if _, err := SyncFolder(t.Context(), workDir, Options{}); err != nil {
+		t.Fatalf("SyncFolder: %v", err)
+	}
+
+	if got := readFileOrFail(t, filepath.Join(workDir, "make/_shared/go/01_mod.mk")); got != "upstream\n" {
+		t.Errorf("01_mod.mk = %q, want %q", got, "upstream\n")
+	}
+
+	targets, err := mod.WorkDir(workDir).ReadTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := targets["make/_shared"][0].KloneSource
+	if want := "https://git.example.invalid/" + filepath.Base(repo); src.RepoURL != want || src.RepoHash != hash {
+		t.Errorf("Expected klone.yaml to keep %s and pin %s, but got %s@%s", want, hash, src.RepoURL, src.RepoHash)
+	}
+	if _, cached, err := cache.Lookup(t.Context(), src); err != nil || !cached {
+		t.Errorf("Expected the content to be cached under the canonical URL, but got %v, %v", cached, err)
+	}
+	if mirrors, err := mod.WorkDir(workDir).ReadMirrors(); err != nil || len(mirrors) != 1 {
+		t.Errorf("Expected the mirror to be kept in klone.yaml, but got %v, %v", mirrors, err)
+	}
+}
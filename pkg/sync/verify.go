@@ -0,0 +1,129 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/download"
+	"github.com/cert-manager/klone/pkg/mod"
+)
+
+// ItemDrift lists the differences between a kloned folder and the pinned
+// upstream content it should contain.
+type ItemDrift struct {
+	Target     string
+	FolderName string
+	Source     mod.KloneSource
+	Diffs      []cache.Diff
+}
+
+// VerifyFolder compares every item in the klone file of workDirPath with
+// its pinned upstream content, with its patches applied, and returns the
+// items that drifted. Neither the destination folders nor the klone file
+// are modified; upstream content is materialised in the klone cache.
+func VerifyFolder(ctx context.Context, workDirPath string) ([]ItemDrift, error) {
+	workDirPath, err := resolveWorkDir(workDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = withConfig(ctx, mod.WorkDir(workDirPath))
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := mod.WorkDir(workDirPath).ReadTargets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	var drifts []ItemDrift
+	if err := forEachItem(workDirPath, targets, func(target string, folderPath string, src mod.KloneItem) error {
+		if src.Pin() == "" {
+			return fmt.Errorf("%s/%s is not pinned, run \"klone sync\" to pin it", target, src.FolderName)
+		}
+
+		cachePath, err := cache.FetchToCache(ctx, src.KloneSource, download.Get)
+		if err != nil {
+			return err
+		}
+
+		contentPath, cleanup, err := materialise(ctx, workDirPath, target, src, cachePath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		diffs, err := cache.CompareTrees(contentPath, folderPath)
+		if err != nil {
+			return err
+		}
+
+		if len(diffs) > 0 {
+			drifts = append(drifts, ItemDrift{
+				Target:     target,
+				FolderName: src.FolderName,
+				Source:     src.KloneSource,
+				Diffs:      diffs,
+			})
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return drifts, nil
+}
+
+// forEachItem calls fn for every item of targets, in a stable order, with
+// the path of the item's destination folder. The same symlink checks as in
+// SyncFolder are applied to every destination before fn is called.
+func forEachItem(workDirPath string, targets map[string]mod.KloneFolder, fn func(target string, folderPath string, src mod.KloneItem) error) error {
+	for _, target := range slices.Sorted(maps.Keys(targets)) {
+		if err := cache.AssertNoSymlinkInSubpath(workDirPath, target); err != nil {
+			return err
+		}
+		targetRoot := filepath.Join(workDirPath, target)
+
+		for _, src := range targets[target] {
+			segments, err := splitFolderName(src.FolderName)
+			if err != nil {
+				return err
+			}
+			canonical := filepath.Join(segments...)
+
+			if err := cache.AssertNoSymlinkInSubpath(targetRoot, canonical); err != nil {
+				return err
+			}
+
+			src.RepoPath = cleanRelativePath(src.RepoPath)
+
+			if err := fn(target, filepath.Join(targetRoot, canonical), src); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2026 The cert-manager Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/cert-manager/klone/pkg/cache"
+	"github.com/cert-manager/klone/pkg/internal/testutil"
+)
+
+func TestVerifyFolder(t *testing.T) {
+	repo, hash := testutil.NewGitRepoWithFiles(t, map[string]string{
+		"modules/go/01_mod.mk": "upstream\n",
+		"modules/go/README.md": "readme\n",
+		"unrelated.txt":        "unrelated\n",
+	})
+
+	workDir := t.TempDir()
+	manifest := `targets:
+  make/_shared:
+    - folder_name: go
+      repo_url: ` + repo + `
+      repo_ref: main
+      repo_hash: ` + hash + `
+      repo_path: modules/go
+`
+	testutil.WriteFiles(t, workDir, map[string]string{
+		"klone.yaml":                 manifest,
+		"make/_shared/go/01_mod.mk":  "upstream\n",
+		"make/_shared/go/README.md":  "readme\n",
+		"make/_shared/go/.gitignore": "",
+	})
+	t.Setenv("KLONE_CACHE_DIR", filepath.Join(t.TempDir(), "cache"))
+
+	drifts, err := VerifyFolder(t.Context(), workDir)
+	if err != nil {
+		t.Fatalf("VerifyFolder: %v", err)
+	}
+	if len(drifts) != 1 {
+		t.Fatalf("VerifyFolder returned %d drifts, want 1", len(drifts))
+	}
+	want := []cache.Diff{{Path: ".gitignore", Kind: cache.DiffAdded}}
+	if drifts[0].Target != "make/_shared" || drifts[0].FolderName != "go" || !slices.Equal(drifts[0].Diffs, want) {
+		t.Errorf("VerifyFolder drift = %+v, want make/_shared/go with %v", drifts[0], want)
+	}
+
+	// Restore the folder: verify must now report no drift, and must not
+	// have rewritten klone.yaml.
+	if err := os.Remove(filepath.Join(workDir, "make/_shared/go/.gitignore")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	drifts, err = VerifyFolder(t.Context(), workDir)
+	if err != nil {
+		t.Fatalf("VerifyFolder: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("VerifyFolder returned drifts %+v, want none", drifts)
+	}
+
+	got, err := os.ReadFile(filepath.Join(workDir, "klone.yaml"))
+	if err != nil {
+		t.Fatalf("read klone.yaml: %v", err)
+	}
+	if string(got) != manifest {
+		t.Errorf("VerifyFolder modified klone.yaml:\n%s", got)
+	}
+}